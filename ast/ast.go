@@ -11,43 +11,158 @@ type Node interface {
 
 type Program struct {
 	File     *token.File
+	Funcs    []*FuncDecl
+	Specials []*SpecialBlock
 	Patterns []*PatternAction
 }
 
 func (p *Program) Pos() token.Pos {
-	if len(p.Patterns) == 0 {
-		return token.NoPos
+	pos := token.NoPos
+	for _, n := range p.nodes() {
+		if !pos.IsValid() || n.Pos() < pos {
+			pos = n.Pos()
+		}
 	}
-	return p.Patterns[0].Pos()
+	return pos
 }
 func (p *Program) End() token.Pos {
-	if len(p.Patterns) == 0 {
-		return token.NoPos
+	end := token.NoPos
+	for _, n := range p.nodes() {
+		if n.End() > end {
+			end = n.End()
+		}
 	}
-	return p.Patterns[len(p.Patterns)-1].End()
+	return end
 }
 
-type PatternAction struct {
-	Pattern *QueryPattern
+// nodes are all the top-level declarations in the program.
+func (p *Program) nodes() (nodes []Node) {
+	for _, f := range p.Funcs {
+		nodes = append(nodes, f)
+	}
+	for _, s := range p.Specials {
+		nodes = append(nodes, s)
+	}
+	for _, pa := range p.Patterns {
+		nodes = append(nodes, pa)
+	}
+	return nodes
+}
+
+// SpecialBlock is an action that runs at a fixed point instead of on a match, like AWK's
+// BEGIN and END. BEGIN and END run once for the whole run, BEGINFILE and ENDFILE around
+// every input file.
+type SpecialBlock struct {
+	KindPos token.Pos
+	Kind    token.Type // BEGIN, END, BEGINFILE or ENDFILE
 	Action  *Action
 }
 
+func (s *SpecialBlock) Pos() token.Pos { return s.KindPos }
+func (s *SpecialBlock) End() token.Pos { return s.Action.End() }
+
+// FuncDecl is a user-defined function: func name(a, b) { ... }.
+type FuncDecl struct {
+	Func   token.Pos
+	Name   *Ident
+	Lparen token.Pos
+	Params []*Ident
+	Rparen token.Pos
+	Body   *Block
+}
+
+func (f *FuncDecl) Pos() token.Pos { return f.Func }
+func (f *FuncDecl) End() token.Pos { return f.Body.End() }
+
+// PatternAction runs Action for every match of Pattern. If there's a Guard (where cond), only
+// the matches for which it's true run the action, and the same for each of the Constraints.
+// Without an action, the match is printed.
+type PatternAction struct {
+	Pattern     Pattern
+	Where       token.Pos // or NoPos if there's no guard
+	Guard       Expr      // or nil
+	Constraints []*Constraint
+	Action      *Action // or nil
+}
+
 func (pa *PatternAction) Pos() token.Pos {
 	if pa.Pattern != nil {
 		return pa.Pattern.Pos()
 	}
 	return pa.Action.Pos()
 }
-func (pa *PatternAction) End() token.Pos { return pa.Action.End() }
+func (pa *PatternAction) End() token.Pos {
+	switch {
+	case pa.Action != nil:
+		return pa.Action.End()
+	case len(pa.Constraints) > 0:
+		return pa.Constraints[len(pa.Constraints)-1].End()
+	case pa.Guard != nil:
+		return pa.Guard.End()
+	default:
+		return pa.Pattern.End()
+	}
+}
+
+// Constraint limits the matches of a rule to the ones inside a node that matches Pattern,
+// or that have a descendant that matches it, or with Not, the opposite:
+//
+//	(call_expression) @c not inside (try_statement)
+type Constraint struct {
+	NotPos  token.Pos // or NoPos
+	KindPos token.Pos
+	Kind    token.Type // INSIDE or HAS
+	Pattern Pattern
+	Where   token.Pos // or NoPos if there's no guard
+	Guard   Expr      // or nil
+}
+
+func (c *Constraint) Pos() token.Pos {
+	if c.NotPos.IsValid() {
+		return c.NotPos
+	}
+	return c.KindPos
+}
+func (c *Constraint) End() token.Pos {
+	if c.Guard != nil {
+		return c.Guard.End()
+	}
+	return c.Pattern.End()
+}
+
+// Pattern is a tree-sitter query pattern: a node like (identifier) or an alternation
+// like [(identifier) (number)].
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+// Capture is the capture of pattern like @id, or nil if there's none.
+func Capture(pattern Pattern) *Ident {
+	switch x := pattern.(type) {
+	case *QueryPattern:
+		return x.Capture
+	case *QueryAlternation:
+		return x.Capture
+	case *AnonPattern:
+		return x.Capture
+	case *ExamplePattern:
+		return x.Capture
+	}
+	return nil
+}
 
 // QueryPattern is a tree-sitter query pattern like (identifier) @id. It's a basic
-// LISP like tree structure.
+// LISP like tree structure. A pattern without a Symbol groups its Args, like
+// ((identifier) @id (#eq? @id "x")).
 type QueryPattern struct {
-	Lparen  token.Pos
-	Symbol  *Ident
-	Args    []Node
-	Rparen  token.Pos
-	Capture *Ident
+	Lparen     token.Pos
+	Symbol     *Ident
+	Args       []Node
+	Rparen     token.Pos
+	QuantPos   token.Pos  // or NoPos if there's no quantifier
+	Quantifier token.Type // STAR, PLUS or QUESTION
+	Capture    *Ident
 }
 
 func (qp *QueryPattern) Pos() token.Pos {
@@ -58,9 +173,74 @@ func (qp *QueryPattern) End() token.Pos {
 	if qp.Capture != nil {
 		return qp.Capture.End()
 	}
+	if qp.QuantPos.IsValid() {
+		return qp.QuantPos
+	}
 	return qp.Rparen
 }
 
+// QueryAlternation matches any one of its Branches, like [(call_expression) (new_expression)] @c.
+type QueryAlternation struct {
+	Lbrack     token.Pos
+	Branches   []Pattern
+	Rbrack     token.Pos
+	QuantPos   token.Pos  // or NoPos if there's no quantifier
+	Quantifier token.Type // STAR, PLUS or QUESTION
+	Capture    *Ident
+}
+
+func (qa *QueryAlternation) Pos() token.Pos { return qa.Lbrack }
+func (qa *QueryAlternation) End() token.Pos {
+	if qa.Capture != nil {
+		return qa.Capture.End()
+	}
+	if qa.QuantPos.IsValid() {
+		return qa.QuantPos
+	}
+	return qa.Rbrack
+}
+
+// AnonPattern matches an anonymous node like "!=" @op, or any node with the bare wildcard
+// _, where (_) only matches named nodes.
+type AnonPattern struct {
+	Name       Node       // *String, or *Ident for _
+	QuantPos   token.Pos  // or NoPos if there's no quantifier
+	Quantifier token.Type // STAR, PLUS or QUESTION
+	Capture    *Ident
+}
+
+func (ap *AnonPattern) Pos() token.Pos { return ap.Name.Pos() }
+func (ap *AnonPattern) End() token.Pos {
+	if ap.Capture != nil {
+		return ap.Capture.End()
+	}
+	if ap.QuantPos.IsValid() {
+		return ap.QuantPos
+	}
+	return ap.Name.End()
+}
+
+// ExamplePattern is a snippet of code in the target language like `let @v@ = null`, where
+// @name@ holes match any node and are captured. It's compiled into a query for each language.
+type ExamplePattern struct {
+	ValuePos token.Pos
+	Value    string // without the backticks
+	Capture  *Ident
+}
+
+func (ep *ExamplePattern) Pos() token.Pos { return ep.ValuePos }
+func (ep *ExamplePattern) End() token.Pos {
+	if ep.Capture != nil {
+		return ep.Capture.End()
+	}
+	return ep.ValuePos + token.Pos(len(ep.Value)+2)
+}
+
+func (qp *QueryPattern) patternNode()     {}
+func (qa *QueryAlternation) patternNode() {}
+func (ap *AnonPattern) patternNode()      {}
+func (ep *ExamplePattern) patternNode()   {}
+
 type PatternField struct {
 	Name  *Ident
 	Colon token.Pos
@@ -69,6 +249,36 @@ type PatternField struct {
 func (pf *PatternField) Pos() token.Pos { return pf.Name.Pos() }
 func (pf *PatternField) End() token.Pos { return pf.Colon }
 
+// NegatedField matches nodes that don't have the field, like !type_parameters.
+type NegatedField struct {
+	Bang token.Pos
+	Name *Ident
+}
+
+func (nf *NegatedField) Pos() token.Pos { return nf.Bang }
+func (nf *NegatedField) End() token.Pos { return nf.Name.End() }
+
+// Anchor is a . in a pattern, which makes the patterns next to it match the first or last
+// child, or immediate siblings, like ((comment) . (function_declaration)).
+type Anchor struct {
+	Period token.Pos
+}
+
+func (a *Anchor) Pos() token.Pos { return a.Period }
+func (a *Anchor) End() token.Pos { return a.Period }
+
+// QueryPredicate is a tree-sitter predicate like (#match? @id "^[A-Z]") that filters the
+// matches of the pattern it's in. Args are captures (*Ident) or strings (*String).
+type QueryPredicate struct {
+	Lparen token.Pos
+	Name   *Ident // e.g. #eq?
+	Args   []Expr
+	Rparen token.Pos
+}
+
+func (qp *QueryPredicate) Pos() token.Pos { return qp.Lparen }
+func (qp *QueryPredicate) End() token.Pos { return qp.Rparen }
+
 // Action is a block with a series of statements that will be executed when the pattern
 // is matched.
 type Action struct {
@@ -85,7 +295,40 @@ type Stmt interface {
 	stmtNode()
 }
 
-func (c *Call) stmtNode() {}
+func (c *Call) stmtNode()         {}
+func (a *AssignStmt) stmtNode()   {}
+func (s *IncDecStmt) stmtNode()   {}
+func (b *Block) stmtNode()        {}
+func (s *IfStmt) stmtNode()       {}
+func (s *WhileStmt) stmtNode()    {}
+func (s *ForStmt) stmtNode()      {}
+func (s *ForInStmt) stmtNode()    {}
+func (s *BranchStmt) stmtNode()   {}
+func (s *DeleteStmt) stmtNode()   {}
+func (s *ReturnStmt) stmtNode()   {}
+func (s *RedirectStmt) stmtNode() {}
+func (x *GetlineExpr) stmtNode()  {}
+
+// AssignStmt assigns Rhs to Lhs (x = 1) or updates it in place (x += 1, x -= 1).
+type AssignStmt struct {
+	Lhs    Expr
+	TokPos token.Pos
+	Tok    token.Type // EQUAL, PLUS_EQUAL or MINUS_EQUAL
+	Rhs    Expr
+}
+
+func (a *AssignStmt) Pos() token.Pos { return a.Lhs.Pos() }
+func (a *AssignStmt) End() token.Pos { return a.Rhs.End() }
+
+// IncDecStmt increments or decrements X by one (x++ or x--).
+type IncDecStmt struct {
+	X      Expr
+	TokPos token.Pos
+	Tok    token.Type // PLUS_PLUS or MINUS_MINUS
+}
+
+func (s *IncDecStmt) Pos() token.Pos { return s.X.Pos() }
+func (s *IncDecStmt) End() token.Pos { return s.TokPos + 2 }
 
 type Call struct {
 	FuncName *Ident
@@ -113,6 +356,224 @@ type String struct {
 func (s *String) Pos() token.Pos { return s.ValuePos }
 func (s *String) End() token.Pos { return s.ValuePos + token.Pos(len(s.Value)) + 2 } // +2 for quotes
 
+// Block is a braced list of statements, used as the body of if, while and for.
+type Block struct {
+	OpenCurly  token.Pos
+	Stmts      []Stmt
+	CloseCurly token.Pos
+}
+
+func (b *Block) Pos() token.Pos { return b.OpenCurly }
+func (b *Block) End() token.Pos { return b.CloseCurly + 1 }
+
+// IfStmt is if Cond { Body } with an optional else branch. Else is either another
+// *IfStmt (else if) or a *Block.
+type IfStmt struct {
+	If   token.Pos
+	Cond Expr
+	Body *Block
+	Else Stmt
+}
+
+func (s *IfStmt) Pos() token.Pos { return s.If }
+func (s *IfStmt) End() token.Pos {
+	if s.Else != nil {
+		return s.Else.End()
+	}
+	return s.Body.End()
+}
+
+// WhileStmt is while Cond { Body }.
+type WhileStmt struct {
+	While token.Pos
+	Cond  Expr
+	Body  *Block
+}
+
+func (s *WhileStmt) Pos() token.Pos { return s.While }
+func (s *WhileStmt) End() token.Pos { return s.Body.End() }
+
+// ForStmt is a C-style loop: for Init; Cond; Post { Body }. Init, Cond and Post may be nil.
+type ForStmt struct {
+	For  token.Pos
+	Init Stmt
+	Cond Expr
+	Post Stmt
+	Body *Block
+}
+
+func (s *ForStmt) Pos() token.Pos { return s.For }
+func (s *ForStmt) End() token.Pos { return s.Body.End() }
+
+// ForInStmt is for Key in X { Body }, iterating over the keys of a dict.
+type ForInStmt struct {
+	For  token.Pos
+	Key  *Ident
+	In   token.Pos
+	X    Expr
+	Body *Block
+}
+
+func (s *ForInStmt) Pos() token.Pos { return s.For }
+func (s *ForInStmt) End() token.Pos { return s.Body.End() }
+
+// BranchStmt is break or continue.
+type BranchStmt struct {
+	TokPos token.Pos
+	Tok    token.Type // BREAK or CONTINUE
+}
+
+func (s *BranchStmt) Pos() token.Pos { return s.TokPos }
+func (s *BranchStmt) End() token.Pos { return s.TokPos + token.Pos(len(s.Tok.Op())) }
+
+// DeleteStmt is delete X, where X is either an entry of a dict (delete d[k]) or a whole
+// dict (delete d) to remove all of its entries.
+type DeleteStmt struct {
+	Delete token.Pos
+	X      Expr
+}
+
+func (s *DeleteStmt) Pos() token.Pos { return s.Delete }
+func (s *DeleteStmt) End() token.Pos { return s.X.End() }
+
+// GetlineExpr is cmd | getline var, which reads the next line of the output of cmd into var.
+// It is both an expression and a statement.
+type GetlineExpr struct {
+	Cmd     Expr
+	Pipe    token.Pos
+	Getline token.Pos
+	Var     Expr
+}
+
+func (x *GetlineExpr) Pos() token.Pos { return x.Cmd.Pos() }
+func (x *GetlineExpr) End() token.Pos { return x.Var.End() }
+
+// ReturnStmt returns from a function, with an optional Result.
+type ReturnStmt struct {
+	Return token.Pos
+	Result Expr // or nil
+}
+
+func (s *ReturnStmt) Pos() token.Pos { return s.Return }
+func (s *ReturnStmt) End() token.Pos {
+	if s.Result != nil {
+		return s.Result.End()
+	}
+	return s.Return + token.Pos(len("return"))
+}
+
+// RedirectStmt sends the output of a print or printf call somewhere else than the standard
+// output: print(x) > "file" truncates the file, print(x) >> "file" appends to it and
+// print(x) | "cmd" writes to the input of a command.
+type RedirectStmt struct {
+	Call  *Call
+	OpPos token.Pos
+	Op    token.Type // GREATER, GREATER_GREATER or PIPE
+	Dest  Expr
+}
+
+func (s *RedirectStmt) Pos() token.Pos { return s.Call.Pos() }
+func (s *RedirectStmt) End() token.Pos { return s.Dest.End() }
+
+// IndexExpr is X[Index], like d["key"].
+type IndexExpr struct {
+	X      Expr
+	Lbrack token.Pos
+	Index  Expr
+	Rbrack token.Pos
+}
+
+func (x *IndexExpr) Pos() token.Pos { return x.X.Pos() }
+func (x *IndexExpr) End() token.Pos { return x.Rbrack + 1 }
+
+// SliceExpr is X[Low:High], like s[1:3]. Low and High may be nil.
+type SliceExpr struct {
+	X      Expr
+	Lbrack token.Pos
+	Low    Expr
+	High   Expr
+	Rbrack token.Pos
+}
+
+func (x *SliceExpr) Pos() token.Pos { return x.X.Pos() }
+func (x *SliceExpr) End() token.Pos { return x.Rbrack + 1 }
+
+// SelectorExpr is X.Sel, like d.key or @fn.name.
+type SelectorExpr struct {
+	X   Expr
+	Sel *Ident
+}
+
+func (x *SelectorExpr) Pos() token.Pos { return x.X.Pos() }
+func (x *SelectorExpr) End() token.Pos { return x.Sel.End() }
+
+// BinaryExpr is X Op Y, like a == b.
+type BinaryExpr struct {
+	X     Expr
+	OpPos token.Pos
+	Op    token.Type
+	Y     Expr
+}
+
+func (b *BinaryExpr) Pos() token.Pos { return b.X.Pos() }
+func (b *BinaryExpr) End() token.Pos { return b.Y.End() }
+
+// UnaryExpr is Op X, like -x or !x.
+type UnaryExpr struct {
+	OpPos token.Pos
+	Op    token.Type // MINUS or BANG
+	X     Expr
+}
+
+func (u *UnaryExpr) Pos() token.Pos { return u.OpPos }
+func (u *UnaryExpr) End() token.Pos { return u.X.End() }
+
+// ConcatExpr is the AWK-style string concatenation of two adjacent expressions, like "a" b.
+type ConcatExpr struct {
+	X, Y Expr
+}
+
+func (c *ConcatExpr) Pos() token.Pos { return c.X.Pos() }
+func (c *ConcatExpr) End() token.Pos { return c.Y.End() }
+
+// ParenExpr is a parenthesized expression (X).
+type ParenExpr struct {
+	Lparen token.Pos
+	X      Expr
+	Rparen token.Pos
+}
+
+func (p *ParenExpr) Pos() token.Pos { return p.Lparen }
+func (p *ParenExpr) End() token.Pos { return p.Rparen + 1 }
+
+// Int is an integer literal like 42. Value is the literal as written in source.
+type Int struct {
+	ValuePos token.Pos
+	Value    string
+}
+
+func (i *Int) Pos() token.Pos { return i.ValuePos }
+func (i *Int) End() token.Pos { return i.ValuePos + token.Pos(len(i.Value)) }
+
+// Float is a floating point literal like 1.5 or 1e3. Value is the literal as written in source.
+type Float struct {
+	ValuePos token.Pos
+	Value    string
+}
+
+func (f *Float) Pos() token.Pos { return f.ValuePos }
+func (f *Float) End() token.Pos { return f.ValuePos + token.Pos(len(f.Value)) }
+
+// Regex is a regular expression literal like /fo+/. Value is the expression between the
+// slashes, with any escaped \/ unescaped.
+type Regex struct {
+	ValuePos token.Pos
+	Value    string
+}
+
+func (r *Regex) Pos() token.Pos { return r.ValuePos }
+func (r *Regex) End() token.Pos { return r.ValuePos + token.Pos(len(r.Value)+2) }
+
 type Dict struct {
 	LCurly  token.Pos
 	Entries []*DictEntry
@@ -122,6 +583,16 @@ type Dict struct {
 func (d *Dict) Pos() token.Pos { return d.LCurly }
 func (d *Dict) End() token.Pos { return d.RCurly }
 
+// List is a list literal like [a, b, c].
+type List struct {
+	Lbrack token.Pos
+	Elts   []Expr
+	Rbrack token.Pos
+}
+
+func (l *List) Pos() token.Pos { return l.Lbrack }
+func (l *List) End() token.Pos { return l.Rbrack + 1 }
+
 type DictEntry struct {
 	Key, Val Expr
 	Colon    token.Pos
@@ -153,7 +624,19 @@ func (b *BadExpr) End() token.Pos {
 	return b.To
 }
 
-func (c *Call) exprNode()   {}
-func (i *Ident) exprNode()  {}
-func (s *String) exprNode() {}
-func (d *Dict) exprNode()   {}
+func (c *Call) exprNode()         {}
+func (i *Ident) exprNode()        {}
+func (s *String) exprNode()       {}
+func (i *Int) exprNode()          {}
+func (b *BinaryExpr) exprNode()   {}
+func (u *UnaryExpr) exprNode()    {}
+func (c *ConcatExpr) exprNode()   {}
+func (p *ParenExpr) exprNode()    {}
+func (f *Float) exprNode()        {}
+func (x *IndexExpr) exprNode()    {}
+func (x *SliceExpr) exprNode()    {}
+func (x *SelectorExpr) exprNode() {}
+func (d *Dict) exprNode()         {}
+func (l *List) exprNode()         {}
+func (r *Regex) exprNode()        {}
+func (x *GetlineExpr) exprNode()  {}
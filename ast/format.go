@@ -3,6 +3,9 @@ package ast
 import (
 	"bytes"
 	"fmt"
+	"strings"
+
+	"github.com/masp/awktree/token"
 )
 
 // Format creates reverses parse and creates a valid program from a given AST.
@@ -16,26 +19,111 @@ func Format(x Node) string {
 func format(x Node, buf *bytes.Buffer) {
 	switch x := x.(type) {
 	case *Program:
+		for _, f := range x.Funcs {
+			format(f, buf)
+			fmt.Fprintf(buf, "\n")
+		}
+		for _, special := range x.Specials {
+			format(special, buf)
+			fmt.Fprintf(buf, "\n")
+		}
 		for _, pa := range x.Patterns {
 			format(pa, buf)
 			fmt.Fprintf(buf, "\n")
 		}
+	case *FuncDecl:
+		buf.WriteString("func ")
+		format(x.Name, buf)
+		buf.WriteString("(")
+		for i, param := range x.Params {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			format(param, buf)
+		}
+		buf.WriteString(") ")
+		format(x.Body, buf)
+	case *SpecialBlock:
+		buf.WriteString(x.Kind.Op() + " ")
+		format(x.Action, buf)
 	case *PatternAction:
 		format(x.Pattern, buf)
-		format(x.Action, buf)
+		if Capture(x.Pattern) != nil {
+			buf.WriteString(" ")
+		}
+		if x.Guard != nil {
+			if Capture(x.Pattern) == nil {
+				buf.WriteString(" ")
+			}
+			buf.WriteString("where ")
+			format(x.Guard, buf)
+			buf.WriteString(" ")
+		}
+		for _, c := range x.Constraints {
+			if !bytes.HasSuffix(buf.Bytes(), []byte(" ")) {
+				buf.WriteString(" ")
+			}
+			format(c, buf)
+			buf.WriteString(" ")
+		}
+		if x.Action != nil {
+			format(x.Action, buf)
+		}
+	case *Constraint:
+		if x.NotPos.IsValid() {
+			buf.WriteString("not ")
+		}
+		buf.WriteString(x.Kind.Op() + " ")
+		format(x.Pattern, buf)
+		if x.Guard != nil {
+			buf.WriteString(" where ")
+			format(x.Guard, buf)
+		}
 	case *QueryPattern:
 		buf.WriteString("(")
-		format(x.Symbol, buf)
+		if x.Symbol != nil {
+			format(x.Symbol, buf)
+		}
+		for i, arg := range x.Args {
+			if i > 0 || x.Symbol != nil {
+				buf.WriteString(" ")
+			}
+			format(arg, buf)
+		}
+		buf.WriteString(")")
+		formatSuffix(x.QuantPos, x.Quantifier, x.Capture, buf)
+	case *QueryAlternation:
+		buf.WriteString("[")
+		for i, branch := range x.Branches {
+			if i > 0 {
+				buf.WriteString(" ")
+			}
+			format(branch, buf)
+		}
+		buf.WriteString("]")
+		formatSuffix(x.QuantPos, x.Quantifier, x.Capture, buf)
+	case *QueryPredicate:
+		buf.WriteString("(")
+		format(x.Name, buf)
 		for _, arg := range x.Args {
 			buf.WriteString(" ")
 			format(arg, buf)
 		}
 		buf.WriteString(")")
+	case *AnonPattern:
+		format(x.Name, buf)
+		formatSuffix(x.QuantPos, x.Quantifier, x.Capture, buf)
+	case *ExamplePattern:
+		buf.WriteString("`" + x.Value + "`")
 		if x.Capture != nil {
 			buf.WriteString(" ")
 			format(x.Capture, buf)
-			buf.WriteString(" ")
 		}
+	case *NegatedField:
+		buf.WriteString("!")
+		format(x.Name, buf)
+	case *Anchor:
+		buf.WriteString(".")
 	case *PatternField:
 		format(x.Name, buf)
 		buf.WriteString(":")
@@ -58,10 +146,133 @@ func format(x Node, buf *bytes.Buffer) {
 			format(arg, buf)
 		}
 		buf.WriteString(")")
+	case *AssignStmt:
+		format(x.Lhs, buf)
+		buf.WriteString(" " + x.Tok.Op() + " ")
+		format(x.Rhs, buf)
+	case *IncDecStmt:
+		format(x.X, buf)
+		buf.WriteString(x.Tok.Op())
+	case *Block:
+		buf.WriteString("{")
+		for i, stmt := range x.Stmts {
+			if i > 0 {
+				buf.WriteString(";")
+			}
+			format(stmt, buf)
+		}
+		buf.WriteString("}")
+	case *IfStmt:
+		buf.WriteString("if ")
+		format(x.Cond, buf)
+		buf.WriteString(" ")
+		format(x.Body, buf)
+		if x.Else != nil {
+			buf.WriteString(" else ")
+			format(x.Else, buf)
+		}
+	case *WhileStmt:
+		buf.WriteString("while ")
+		format(x.Cond, buf)
+		buf.WriteString(" ")
+		format(x.Body, buf)
+	case *ForStmt:
+		buf.WriteString("for ")
+		if x.Init != nil {
+			format(x.Init, buf)
+		}
+		buf.WriteString(";")
+		if x.Cond != nil {
+			buf.WriteString(" ")
+			format(x.Cond, buf)
+		}
+		buf.WriteString(";")
+		if x.Post != nil {
+			buf.WriteString(" ")
+			format(x.Post, buf)
+		}
+		buf.WriteString(" ")
+		format(x.Body, buf)
+	case *ForInStmt:
+		buf.WriteString("for ")
+		format(x.Key, buf)
+		buf.WriteString(" in ")
+		format(x.X, buf)
+		buf.WriteString(" ")
+		format(x.Body, buf)
+	case *BranchStmt:
+		buf.WriteString(x.Tok.Op())
+	case *BinaryExpr:
+		format(x.X, buf)
+		buf.WriteString(" " + x.Op.Op() + " ")
+		format(x.Y, buf)
+	case *DeleteStmt:
+		buf.WriteString("delete ")
+		format(x.X, buf)
+	case *RedirectStmt:
+		format(x.Call, buf)
+		buf.WriteString(" " + x.Op.Op() + " ")
+		format(x.Dest, buf)
+	case *GetlineExpr:
+		format(x.Cmd, buf)
+		buf.WriteString(" | getline ")
+		format(x.Var, buf)
+	case *ReturnStmt:
+		buf.WriteString("return")
+		if x.Result != nil {
+			buf.WriteString(" ")
+			format(x.Result, buf)
+		}
+	case *IndexExpr:
+		format(x.X, buf)
+		buf.WriteString("[")
+		format(x.Index, buf)
+		buf.WriteString("]")
+	case *SliceExpr:
+		format(x.X, buf)
+		buf.WriteString("[")
+		if x.Low != nil {
+			format(x.Low, buf)
+		}
+		buf.WriteString(":")
+		if x.High != nil {
+			format(x.High, buf)
+		}
+		buf.WriteString("]")
+	case *SelectorExpr:
+		format(x.X, buf)
+		buf.WriteString(".")
+		format(x.Sel, buf)
+	case *UnaryExpr:
+		buf.WriteString(x.Op.Op())
+		format(x.X, buf)
+	case *ConcatExpr:
+		format(x.X, buf)
+		buf.WriteString(" ")
+		format(x.Y, buf)
+	case *ParenExpr:
+		buf.WriteString("(")
+		format(x.X, buf)
+		buf.WriteString(")")
+	case *Float:
+		buf.WriteString(x.Value)
 	case *String:
 		buf.WriteString(`"` + x.Value + `"`)
+	case *Int:
+		buf.WriteString(x.Value)
+	case *Regex:
+		buf.WriteString("/" + strings.ReplaceAll(x.Value, "/", `\/`) + "/")
 	case *Ident:
 		buf.WriteString(x.Name)
+	case *List:
+		buf.WriteString("[")
+		for i, elt := range x.Elts {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			format(elt, buf)
+		}
+		buf.WriteString("]")
 	case *Dict:
 		buf.WriteString("{")
 		for _, kv := range x.Entries {
@@ -77,3 +288,14 @@ func format(x Node, buf *bytes.Buffer) {
 		buf.WriteString("}")
 	}
 }
+
+// formatSuffix formats the optional quantifier and capture after a pattern, like )+ @stmts.
+func formatSuffix(quantPos token.Pos, quantifier token.Type, capture *Ident, buf *bytes.Buffer) {
+	if quantPos.IsValid() {
+		buf.WriteString(quantifier.Op())
+	}
+	if capture != nil {
+		buf.WriteString(" ")
+		format(capture, buf)
+	}
+}
@@ -32,24 +32,159 @@ func walk(n Node, v Visitor) {
 
 	switch n := n.(type) {
 	case *Program:
+		for _, f := range n.Funcs {
+			walk(f, v)
+		}
+		for _, special := range n.Specials {
+			walk(special, v)
+		}
 		for _, pattern := range n.Patterns {
 			walk(pattern, v)
 		}
+	case *FuncDecl:
+		mustVisit(v, n.Name)
+		for _, param := range n.Params {
+			mustVisit(v, param)
+		}
+		walk(n.Body, v)
+	case *SpecialBlock:
+		walk(n.Action, v)
 	case *PatternAction:
 		walk(n.Pattern, v)
-		walk(n.Action, v)
+		if n.Guard != nil {
+			walk(n.Guard, v)
+		}
+		for _, c := range n.Constraints {
+			walk(c, v)
+		}
+		if n.Action != nil {
+			walk(n.Action, v)
+		}
+	case *Constraint:
+		walk(n.Pattern, v)
+		if n.Guard != nil {
+			walk(n.Guard, v)
+		}
 	case *QueryPattern:
-		mustVisit(v, n.Symbol)
+		if n.Symbol != nil {
+			mustVisit(v, n.Symbol)
+		}
 		for _, arg := range n.Args {
 			walk(arg, v)
 		}
 		if n.Capture != nil {
 			mustVisit(v, n.Capture)
 		}
+	case *QueryAlternation:
+		for _, branch := range n.Branches {
+			walk(branch, v)
+		}
+		if n.Capture != nil {
+			mustVisit(v, n.Capture)
+		}
+	case *AnonPattern:
+		mustVisit(v, n.Name)
+		if n.Capture != nil {
+			mustVisit(v, n.Capture)
+		}
+	case *ExamplePattern:
+		if n.Capture != nil {
+			mustVisit(v, n.Capture)
+		}
+	case *NegatedField:
+		mustVisit(v, n.Name)
+	case *QueryPredicate:
+		mustVisit(v, n.Name)
+		for _, arg := range n.Args {
+			walk(arg, v)
+		}
 	case *Action:
 		for _, stmt := range n.Stmts {
 			walk(stmt, v)
 		}
+	case *AssignStmt:
+		walk(n.Lhs, v)
+		walk(n.Rhs, v)
+	case *IncDecStmt:
+		walk(n.X, v)
+	case *Block:
+		for _, stmt := range n.Stmts {
+			walk(stmt, v)
+		}
+	case *IfStmt:
+		walk(n.Cond, v)
+		walk(n.Body, v)
+		if n.Else != nil {
+			walk(n.Else, v)
+		}
+	case *WhileStmt:
+		walk(n.Cond, v)
+		walk(n.Body, v)
+	case *ForStmt:
+		if n.Init != nil {
+			walk(n.Init, v)
+		}
+		if n.Cond != nil {
+			walk(n.Cond, v)
+		}
+		if n.Post != nil {
+			walk(n.Post, v)
+		}
+		walk(n.Body, v)
+	case *ForInStmt:
+		mustVisit(v, n.Key)
+		walk(n.X, v)
+		walk(n.Body, v)
+	case *BinaryExpr:
+		walk(n.X, v)
+		walk(n.Y, v)
+	case *DeleteStmt:
+		walk(n.X, v)
+	case *RedirectStmt:
+		walk(n.Call, v)
+		walk(n.Dest, v)
+	case *GetlineExpr:
+		walk(n.Cmd, v)
+		walk(n.Var, v)
+	case *ReturnStmt:
+		if n.Result != nil {
+			walk(n.Result, v)
+		}
+	case *IndexExpr:
+		walk(n.X, v)
+		walk(n.Index, v)
+	case *SliceExpr:
+		walk(n.X, v)
+		if n.Low != nil {
+			walk(n.Low, v)
+		}
+		if n.High != nil {
+			walk(n.High, v)
+		}
+	case *SelectorExpr:
+		walk(n.X, v)
+		mustVisit(v, n.Sel)
+	case *UnaryExpr:
+		walk(n.X, v)
+	case *ConcatExpr:
+		walk(n.X, v)
+		walk(n.Y, v)
+	case *ParenExpr:
+		walk(n.X, v)
+	case *Call:
+		mustVisit(v, n.FuncName)
+		for _, arg := range n.Args {
+			walk(arg, v)
+		}
+	case *List:
+		for _, elt := range n.Elts {
+			walk(elt, v)
+		}
+	case *Dict:
+		for _, kv := range n.Entries {
+			walk(kv.Key, v)
+			walk(kv.Val, v)
+		}
 	default:
 		// leaf node, no need to do anything
 		return
@@ -18,6 +18,8 @@ var (
 	flagVersion  = flag.Bool("v", false, "Show version")
 	flagVerbose  = flag.Bool("d", false, "Verbose mode")
 	flagProgFile = flag.String("f", "", "Path to a tra file to execute instead of inline")
+	flagInPlace  = flag.Bool("i", false, "Edit the input files in place (stdin is written to stdout)")
+	flagSandbox  = flag.Bool("sandbox", false, "Don't allow running commands with system, getline or print to a pipe")
 )
 
 func usage() {
@@ -94,20 +96,58 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Close flushes the files written with print(x) > "file", also when the program fails
+	fail := func(err error) {
+		prog.Close()
+		fatalf("tra: error: %v\n", err)
+	}
+
+	ctx := context.Background()
+	err = prog.Begin(ctx, &eval.Options{Stdout: os.Stdout, Stderr: os.Stderr, AllowCommands: !*flagSandbox})
+	if err != nil {
+		fail(err)
+	}
+	if !prog.ReadsInput() {
+		if err := prog.Close(); err != nil {
+			fatalf("tra: error: %v\n", err)
+		}
+		return
+	}
+
 	for _, input := range inputs {
 		src, err := io.ReadAll(input.rd)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "tra: can't open file %s: %v\n", input.filename, err)
 			continue
 		}
-		err = prog.Eval(context.Background(), src, &eval.Options{
-			Filename: input.filename,
-			Stdout:   os.Stdout,
-		})
+		opts := &eval.Options{
+			Filename:      input.filename,
+			Stdout:        os.Stdout,
+			Stderr:        os.Stderr,
+			AllowCommands: !*flagSandbox,
+		}
+		var edits []eval.Edit
+		if *flagInPlace {
+			opts.Edits = &edits
+		}
+		err = prog.Eval(ctx, src, opts)
 		if err != nil {
-			fatalf("tra: error: %v\n", err)
+			fail(err)
+		}
+		if *flagInPlace {
+			if err := writeEdits(input, src, edits); err != nil {
+				fail(err)
+			}
 		}
 	}
+
+	err = prog.End(ctx, &eval.Options{Stdout: os.Stdout, Stderr: os.Stderr, AllowCommands: !*flagSandbox})
+	if err != nil {
+		fail(err)
+	}
+	if err := prog.Close(); err != nil {
+		fatalf("tra: error: %v\n", err)
+	}
 }
 
 func readInputs(inputs []string) []input {
@@ -123,6 +163,27 @@ func readInputs(inputs []string) []input {
 	return readers
 }
 
+// writeEdits applies the edits to the input file, or writes the edited input to stdout if
+// it was read from stdin.
+func writeEdits(in input, src []byte, edits []eval.Edit) error {
+	if len(edits) == 0 && in.filename != "<stdin>" {
+		return nil
+	}
+	out, err := eval.ApplyEdits(src, edits)
+	if err != nil {
+		return fmt.Errorf("%s: %w", in.filename, err)
+	}
+	if in.filename == "<stdin>" {
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+	info, err := os.Stat(in.filename)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(in.filename, out, info.Mode())
+}
+
 type input struct {
 	filename string
 	rd       io.Reader
@@ -0,0 +1,157 @@
+package eval
+
+import (
+	"fmt"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/masp/awktree/ast"
+)
+
+// index evaluates x[index]:
+//   - dict[key] is the entry for key. A missing entry is the empty value, which also acts
+//     as 0, and isn't added to the dict.
+//   - list[i] is the i-th element.
+//   - string[i] is the i-th character (not byte).
+//   - node[i] is the i-th named child and node["name"] the child for the field name.
+func (p *Program) index(x, index Value) (Value, error) {
+	switch x := x.(type) {
+	case *DictVal:
+		v, ok, err := x.Get(index)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return &StringVal{}, nil
+		}
+		return v, nil
+	case *ListVal:
+		i, err := listIndex(x, index)
+		if err != nil {
+			return nil, err
+		}
+		return x.L[i], nil
+	case *StringVal:
+		i, err := toInt(index)
+		if err != nil {
+			return nil, err
+		}
+		runes := []rune(x.S)
+		if i < 0 || i >= len(runes) {
+			return nil, fmt.Errorf("index %d out of range [0:%d]", i, len(runes))
+		}
+		return &StringVal{S: string(runes[i])}, nil
+	case *NodeVal:
+		if name, ok := index.(*StringVal); ok {
+			return x.field(name.S), nil
+		}
+		i, err := toInt(index)
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= int(x.N.NamedChildCount()) {
+			return nil, fmt.Errorf("index %d out of range [0:%d]", i, x.N.NamedChildCount())
+		}
+		return &NodeVal{N: x.N.NamedChild(i), Src: x.Src}, nil
+	default:
+		return nil, fmt.Errorf("cannot index %s", typeName(x))
+	}
+}
+
+// selector evaluates x.name, which is the same as x["name"] for dicts. For nodes, it's one of
+// the properties below or else the child for the field name. Use node["name"] for a field
+// that has the same name as a property.
+func (p *Program) selector(x Value, name string) (Value, error) {
+	switch x := x.(type) {
+	case *NodeVal:
+		if v, ok := x.property(name); ok {
+			return v, nil
+		}
+		return x.field(name), nil
+	case *DictVal:
+		return p.index(x, &StringVal{S: name})
+	default:
+		return nil, fmt.Errorf("%s has no field %s", typeName(x), name)
+	}
+}
+
+// listIndex converts index to a position in l, which has to be in range.
+func listIndex(l *ListVal, index Value) (int, error) {
+	i, err := toInt(index)
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 || i >= len(l.L) {
+		return 0, fmt.Errorf("index %d out of range [0:%d]", i, len(l.L))
+	}
+	return i, nil
+}
+
+// evalSlice evaluates x[low:high] on lists, strings and nodes (using their text). Like AWK's
+// substr, the bounds are clamped to the length, which for strings is counted in characters.
+// Slicing a list makes a new list, so changing it doesn't change the original.
+func (p *Program) evalSlice(c *evalCtx, expr *ast.SliceExpr) (Value, error) {
+	x, err := p.eval(c, expr.X)
+	if err != nil {
+		return nil, err
+	}
+	if l, ok := x.(*ListVal); ok {
+		low, high, err := p.sliceBounds(c, expr, len(l.L))
+		if err != nil {
+			return nil, err
+		}
+		return &ListVal{L: slices.Clone(l.L[low:high])}, nil
+	}
+
+	var s string
+	switch x := x.(type) {
+	case *StringVal:
+		s = x.S
+	case *NodeVal:
+		s = x.Content()
+	default:
+		return nil, fmt.Errorf("cannot slice %s", typeName(x))
+	}
+
+	n := utf8.RuneCountInString(s)
+	low, high, err := p.sliceBounds(c, expr, n)
+	if err != nil {
+		return nil, err
+	}
+	return &StringVal{S: string([]rune(s)[low:high])}, nil
+}
+
+// sliceBounds evaluates the bounds of expr for a sequence of length n.
+func (p *Program) sliceBounds(c *evalCtx, expr *ast.SliceExpr, n int) (low, high int, err error) {
+	low, high = 0, n
+	if expr.Low != nil {
+		if low, err = p.evalInt(c, expr.Low); err != nil {
+			return 0, 0, err
+		}
+	}
+	if expr.High != nil {
+		if high, err = p.evalInt(c, expr.High); err != nil {
+			return 0, 0, err
+		}
+	}
+	low, high = max(0, min(low, n)), max(0, min(high, n))
+	return low, max(low, high), nil
+}
+
+func (p *Program) evalInt(c *evalCtx, x ast.Expr) (int, error) {
+	v, err := p.eval(c, x)
+	if err != nil {
+		return 0, err
+	}
+	return toInt(v)
+}
+
+// selectorIndex rewrites x.name to the equivalent x["name"].
+func selectorIndex(x *ast.SelectorExpr) *ast.IndexExpr {
+	return &ast.IndexExpr{
+		X:      x.X,
+		Lbrack: x.Sel.Pos(),
+		Index:  &ast.String{ValuePos: x.Sel.Pos(), Value: x.Sel.Name},
+		Rbrack: x.Sel.End(),
+	}
+}
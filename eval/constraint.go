@@ -0,0 +1,172 @@
+package eval
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/masp/awktree/ast"
+	"github.com/masp/awktree/token"
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// A rule's constraints look for their pattern around the root capture of each match: inside
+// checks its ancestors and has its descendants. The captures of the constraint can refer to
+// the captures of the rule in its guard, a capture in both must match the same code, and a
+// constraint that holds (without not) binds its own captures for the action.
+
+type constraint struct {
+	*ast.Constraint
+	q       *query
+	matches []constraintMatch // every match of q in the file, sorted by the start of their root
+}
+
+type constraintMatch struct {
+	root *sitter.Node
+	m    *sitter.QueryMatch
+}
+
+// compileConstraints compiles the patterns of constraints and finds all of their matches in
+// the file once, so each match of the rule only has to look up its ancestors or descendants.
+func (l *language) compileConstraints(constraints []*ast.Constraint, file *sitter.Node) ([]constraint, error) {
+	var result []constraint
+	for _, c := range constraints {
+		q, err := l.compileQuery(c.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, constraint{Constraint: c, q: q, matches: allMatches(q, file)})
+	}
+	return result, nil
+}
+
+func allMatches(q *query, file *sitter.Node) (matches []constraintMatch) {
+	qc := sitter.NewQueryCursor()
+	defer qc.Close()
+	qc.Exec(q.q, file)
+	for {
+		m, ok := qc.NextMatch()
+		if !ok {
+			break
+		}
+		if root := matchRoot(q, m); root != nil {
+			matches = append(matches, constraintMatch{root: root, m: m})
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b constraintMatch) int {
+		return cmp.Compare(a.root.StartByte(), b.root.StartByte())
+	})
+	return
+}
+
+// checkConstraints is true if the current match of c satisfies all constraints.
+func (p *Program) checkConstraints(c *evalCtx, constraints []constraint) (bool, error) {
+	for _, cons := range constraints {
+		found, err := p.findConstraint(c, cons)
+		if err != nil {
+			return false, err
+		}
+		if negated := cons.NotPos.IsValid(); found == negated {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// findConstraint is true if the pattern of cons matches an ancestor (inside) or a descendant
+// (has) of the root capture, starting with the closest ancestor or the first descendant.
+func (p *Program) findConstraint(c *evalCtx, cons constraint) (bool, error) {
+	root := c.Vars["@"].(*NodeVal).N
+	if cons.Kind == token.HAS {
+		for _, cm := range cons.within(root.StartByte(), root.EndByte()) {
+			if !cm.root.Equal(root) && isAncestor(root, cm.root) {
+				if found, err := p.acceptConstraint(c, cons, cm.m); found || err != nil {
+					return found, err
+				}
+			}
+		}
+		return false, nil
+	}
+	for ancestor := root.Parent(); ancestor != nil; ancestor = ancestor.Parent() {
+		for _, cm := range cons.within(ancestor.StartByte(), ancestor.StartByte()) {
+			if cm.root.Equal(ancestor) {
+				if found, err := p.acceptConstraint(c, cons, cm.m); found || err != nil {
+					return found, err
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+// within are the matches of cons whose root starts between start and end, inclusive.
+func (cons constraint) within(start, end uint32) []constraintMatch {
+	i, _ := slices.BinarySearchFunc(cons.matches, start, func(cm constraintMatch, start uint32) int {
+		return cmp.Compare(cm.root.StartByte(), start)
+	})
+	j := i
+	for j < len(cons.matches) && cons.matches[j].root.StartByte() <= end {
+		j++
+	}
+	return cons.matches[i:j]
+}
+
+// isAncestor is true if a is n or one of its ancestors.
+func isAncestor(a, n *sitter.Node) bool {
+	for ; n != nil && n.StartByte() >= a.StartByte(); n = n.Parent() {
+		if n.Equal(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptConstraint is true if m is accepted with the captures of c, which it binds unless
+// cons is negated.
+func (p *Program) acceptConstraint(c *evalCtx, cons constraint, m *sitter.QueryMatch) (bool, error) {
+	inner := *c
+	inner.Vars = maps.Clone(c.Vars)
+	found, err := p.accept(&inner, cons.q, m, cons.Guard)
+	if err != nil || !found || !sameCaptures(c, &inner) {
+		return false, err
+	}
+	if !cons.NotPos.IsValid() {
+		maps.DeleteFunc(inner.Vars, func(name string, _ Value) bool { return isInternal(name) })
+		maps.Copy(c.Vars, inner.Vars)
+	}
+	return true, nil
+}
+
+// matchRoot is the first node of the root capture of m.
+func matchRoot(q *query, m *sitter.QueryMatch) *sitter.Node {
+	for _, capture := range m.Captures {
+		if q.q.CaptureNameForId(capture.Index) == q.rootCapture[1:] {
+			return capture.Node
+		}
+	}
+	return nil
+}
+
+// sameCaptures is true if every capture of inner that was already bound in outer is the same
+// code in both.
+func sameCaptures(outer, inner *evalCtx) bool {
+	for id := uint32(0); id < inner.SQuery.CaptureCount(); id++ {
+		name := "@" + inner.SQuery.CaptureNameForId(id)
+		if isInternal(name) {
+			continue
+		}
+		a, ok1 := outer.Vars[name].(*NodeVal)
+		b, ok2 := inner.Vars[name].(*NodeVal)
+		if ok1 && ok2 && !sameCode(a.N, b.N, outer.Src) {
+			return false
+		}
+	}
+	return true
+}
+
+// isInternal is true for the captures added by the compiler, like @__match, which are
+// different in each pattern even if they have the same name.
+func isInternal(name string) bool {
+	return strings.HasPrefix(name, "@__")
+}
@@ -0,0 +1,43 @@
+package eval
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Edit replaces the bytes [Start, End) of an input file with Text.
+type Edit struct {
+	Start, End int
+	Text       string
+}
+
+// edit records replacing the text of n with text.
+func (c *evalCtx) edit(n *NodeVal, text string) {
+	if c.Edits == nil {
+		return
+	}
+	*c.Edits = append(*c.Edits, Edit{Start: int(n.N.StartByte()), End: int(n.N.EndByte()), Text: text})
+}
+
+// ApplyEdits returns src with the edits applied. The edits may be in any order but they
+// can't overlap.
+func ApplyEdits(src []byte, edits []Edit) ([]byte, error) {
+	edits = slices.Clone(edits)
+	slices.SortStableFunc(edits, func(a, b Edit) int { return a.Start - b.Start })
+
+	var out []byte
+	last := 0
+	for i, e := range edits {
+		if e.Start < 0 || e.End < e.Start || e.End > len(src) {
+			return nil, fmt.Errorf("edit of bytes %d-%d is out of range [0:%d]", e.Start, e.End, len(src))
+		}
+		if e.Start < last {
+			prev := edits[i-1]
+			return nil, fmt.Errorf("edit of bytes %d-%d overlaps edit of bytes %d-%d", e.Start, e.End, prev.Start, prev.End)
+		}
+		out = append(out, src[last:e.Start]...)
+		out = append(out, e.Text...)
+		last = e.End
+	}
+	return append(out, src[last:]...), nil
+}
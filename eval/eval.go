@@ -2,16 +2,20 @@ package eval
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/go-enry/go-enry/v2"
 	"github.com/masp/awktree/ast"
 	"github.com/masp/awktree/parser"
+	"github.com/masp/awktree/token"
 	sitter "github.com/smacker/go-tree-sitter"
 	"github.com/smacker/go-tree-sitter/golang"
 	"github.com/smacker/go-tree-sitter/java"
@@ -21,6 +25,15 @@ import (
 
 type Program struct {
 	Ast *ast.Program
+
+	// Global variables live for the whole run, across matches, patterns and files
+	globals     map[string]Value
+	globalNames map[string]bool
+
+	funcs   map[string]*ast.FuncDecl  // user-defined functions by name
+	regexps map[string]*regexp.Regexp // compiled regular expressions by source
+	outputs map[string]output         // files and commands opened by print, see Close
+	inputs  map[string]*inputPipe     // commands opened by getline
 }
 
 func Compile(filename string, src []byte) (*Program, error) {
@@ -28,7 +41,152 @@ func Compile(filename string, src []byte) (*Program, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Program{Ast: prog}, nil
+	p := &Program{
+		Ast:         prog,
+		globals:     make(map[string]Value),
+		globalNames: collectGlobals(prog),
+		funcs:       make(map[string]*ast.FuncDecl),
+		regexps:     make(map[string]*regexp.Regexp),
+		outputs:     make(map[string]output),
+		inputs:      make(map[string]*inputPipe),
+	}
+	for name, def := range outputVars {
+		p.globals[name] = &StringVal{S: def}
+		p.globalNames[name] = true
+	}
+	if err := p.resolveFuncs(); err != nil {
+		return nil, err
+	}
+	if err := p.compileRegexps(); err != nil {
+		return nil, err
+	}
+	if err := p.compilePredicates(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// outputVars are the global variables that control the output of print with their defaults,
+// like AWK: OFS separates the values and ORS ends them.
+var outputVars = map[string]string{
+	"OFS": " ",
+	"ORS": "\n",
+}
+
+// builtins are the functions provided by the language, with the minimum and maximum number
+// of arguments they take (-1 for any number).
+var builtins = map[string]struct{ min, max int }{
+	"print":  {0, -1},
+	"printf": {1, -1},
+	"eprint": {0, -1},
+	"close":  {1, 1},
+	"system": {1, 1},
+	"len":    {1, 1},
+	"append": {1, -1},
+
+	// Tree navigation, see navigate
+	"parent":         {1, 1},
+	"children":       {1, 1},
+	"named_children": {1, 1},
+	"child":          {2, 2},
+	"next_sibling":   {1, 1},
+	"prev_sibling":   {1, 1},
+	"ancestors":      {1, 1},
+	"descendants":    {1, 2},
+	"enclosing":      {2, 2},
+
+	// Strings, see stringFunc
+	"length":  {1, 1},
+	"substr":  {2, 3},
+	"index":   {2, 2},
+	"split":   {1, 2},
+	"sprintf": {1, -1},
+	"toupper": {1, 1},
+	"tolower": {1, 1},
+	"trim":    {1, 2},
+
+	// Regular expressions, see regex.go
+	"match": {2, 2},
+	"sub":   {2, 3},
+	"gsub":  {2, 3},
+}
+
+// resolveFuncs checks that every call refers to a builtin or a user-defined function and has
+// the right number of arguments, so that mistakes are reported before running anything.
+func (p *Program) resolveFuncs() error {
+	var errs token.ErrorList
+	errorf := func(pos token.Pos, format string, args ...any) {
+		errs.Add(p.Ast.File.Position(pos), fmt.Errorf(format, args...))
+	}
+
+	for _, f := range p.Ast.Funcs {
+		name := f.Name.Name
+		if _, ok := builtins[name]; ok {
+			errorf(f.Name.Pos(), "cannot redeclare builtin function %s", name)
+		} else if prev, ok := p.funcs[name]; ok {
+			errorf(f.Name.Pos(), "function %s redeclared, previous declaration at %s", name, p.Ast.File.Position(prev.Pos()))
+		}
+		p.funcs[name] = f
+	}
+
+	ast.Walk(p.Ast, ast.VisitorFunc(func(n ast.Node) error {
+		call, ok := n.(*ast.Call)
+		if !ok {
+			return nil
+		}
+		name := call.FuncName.Name
+		min, max := 0, 0
+		if b, ok := builtins[name]; ok {
+			min, max = b.min, b.max
+		} else if f, ok := p.funcs[name]; ok {
+			min, max = len(f.Params), len(f.Params)
+		} else {
+			errorf(call.Pos(), "unknown function %s", name)
+			return nil
+		}
+		switch n := len(call.Args); {
+		case min == max && n != min:
+			errorf(call.Pos(), "%s expects %d arguments, got %d", name, min, n)
+		case n < min:
+			errorf(call.Pos(), "%s expects at least %d arguments, got %d", name, min, n)
+		case max >= 0 && n > max:
+			errorf(call.Pos(), "%s expects at most %d arguments, got %d", name, max, n)
+		}
+		return nil
+	}))
+	errs.Sort()
+	return errs.Err()
+}
+
+// collectGlobals returns the names of the global variables in prog. Like the BEGIN { n = 0 }
+// and END { print(n) } idioms in AWK, every variable assigned or read in a BEGIN, END,
+// BEGINFILE or ENDFILE block is global and is shared with all actions. Any other variable is
+// local to the action assigning it.
+func collectGlobals(prog *ast.Program) map[string]bool {
+	names := make(map[string]bool)
+	for _, special := range prog.Specials {
+		notVars := make(map[*ast.Ident]bool) // names that aren't variables, like f in f(x)
+		ast.Walk(special, ast.VisitorFunc(func(n ast.Node) error {
+			switch n := n.(type) {
+			case *ast.Call:
+				notVars[n.FuncName] = true
+			case *ast.SelectorExpr:
+				notVars[n.Sel] = true
+			case *ast.Dict:
+				for _, kv := range n.Entries {
+					if key, ok := kv.Key.(*ast.Ident); ok {
+						notVars[key] = true
+					}
+				}
+			case *ast.Ident:
+				if !notVars[n] && !strings.HasPrefix(n.Name, "@") {
+					names[n.Name] = true
+				}
+			}
+			return nil
+		}))
+	}
+	return names
 }
 
 type Options struct {
@@ -36,8 +194,60 @@ type Options struct {
 	Language *sitter.Language // optional, overrides from filename
 
 	Stdout io.Writer // optional, defaults to /dev/null
+	Stderr io.Writer // optional, defaults to /dev/null
+	Edits  *[]Edit   // optional, collects the edits to the input, see ApplyEdits
+
+	// AllowCommands lets the program run shell commands with system(cmd), cmd | getline var
+	// and print(x) | cmd. It's off by default.
+	AllowCommands bool
 }
 
+// Begin runs the BEGIN blocks of the program. It should be called once before the first
+// call to Eval.
+func (p *Program) Begin(ctx context.Context, opts *Options) error {
+	return p.runSpecial(token.BEGIN, p.newEvalCtx(opts))
+}
+
+// End runs the END blocks of the program. It should be called once after the last call
+// to Eval.
+func (p *Program) End(ctx context.Context, opts *Options) error {
+	return p.runSpecial(token.END, p.newEvalCtx(opts))
+}
+
+// ReadsInput is false if the program only has BEGIN blocks, so there is no need to read
+// any input (like awk 'BEGIN { ... }').
+func (p *Program) ReadsInput() bool {
+	if len(p.Ast.Patterns) > 0 {
+		return true
+	}
+	for _, special := range p.Ast.Specials {
+		if special.Kind != token.BEGIN {
+			return true
+		}
+	}
+	return false
+}
+
+// runSpecial runs every special block of the given kind in the order they were declared.
+func (p *Program) runSpecial(kind token.Type, c *evalCtx) error {
+	for _, special := range p.Ast.Specials {
+		if special.Kind != kind {
+			continue
+		}
+		c.Clear()
+		if c.Root != nil {
+			c.Vars["@"] = &NodeVal{N: c.Root, Src: c.Src}
+		}
+		if err := p.runAction(c, special.Action); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Eval runs the program against a single input file: the BEGINFILE blocks, then the action
+// of every pattern for each of its matches and finally the ENDFILE blocks. Inside BEGINFILE
+// and ENDFILE, @ is the root node of the file.
 func (p *Program) Eval(ctx context.Context, src []byte, opts *Options) error {
 	tsLang, err := p.detectLanguage(opts)
 	if err != nil {
@@ -50,43 +260,57 @@ func (p *Program) Eval(ctx context.Context, src []byte, opts *Options) error {
 		return err
 	}
 
+	fileCtx := p.newEvalCtx(opts)
+	fileCtx.Src = src
+	fileCtx.Root = n
+	if err := p.runSpecial(token.BEGINFILE, fileCtx); err != nil {
+		return err
+	}
+
 	for _, pa := range p.Ast.Patterns {
-		var rootCapture string
-		rootCapture, tsPattern, err := lang.formatPattern(pa.Pattern)
+		q, err := lang.compileQuery(pa.Pattern)
 		if err != nil {
 			return err
 		}
-
-		log.Printf("query pattern: %s", tsPattern)
-		q, err := sitter.NewQuery([]byte(tsPattern), tsLang)
+		constraints, err := lang.compileConstraints(pa.Constraints, n)
 		if err != nil {
 			return err
 		}
 		qc := sitter.NewQueryCursor()
-		qc.Exec(q, n)
-		state := &evalCtx{}
-		if opts.Stdout == nil {
-			state.Output = io.Discard
-		} else {
-			state.Output = opts.Stdout
-		}
+		qc.Exec(q.q, n)
+		state := p.newEvalCtx(opts)
 		state.Src = src
 		state.Root = n
-		state.SQuery = q
 		for {
 			state.Clear()
 			m, ok := qc.NextMatch()
 			if !ok {
 				break
 			}
-			state.applyQuery(rootCapture, m)
-			err := p.runAction(state, pa.Action)
+			matched, err := p.accept(state, q, m, pa.Guard)
+			if err != nil {
+				return err
+			}
+			if !matched {
+				continue
+			}
+			if matched, err = p.checkConstraints(state, constraints); err != nil {
+				return err
+			}
+			if !matched {
+				continue
+			}
+			if pa.Action == nil {
+				err = p.print(state, nil)
+			} else {
+				err = p.runAction(state, pa.Action)
+			}
 			if err != nil {
 				return err
 			}
 		}
 	}
-	return nil
+	return p.runSpecial(token.ENDFILE, fileCtx)
 }
 
 func (p *Program) detectLanguage(opts *Options) (*sitter.Language, error) {
@@ -122,75 +346,673 @@ type evalCtx struct {
 	Root   *sitter.Node
 	SQuery *sitter.Query
 
-	// Variable name to value
+	// Captures of the current match by name (e.g. @id), set by applyQuery
 	Vars map[string]Value
+	// User variables local to the running action
+	Locals map[string]Value
+	// Global variables shared by the whole program, see collectGlobals
+	Globals     map[string]Value
+	globalNames map[string]bool
 
 	Output io.Writer
+	Stderr io.Writer
+	Edits  *[]Edit
+
+	allowCommands bool
+
+	depth int // number of user-defined function calls in progress
+}
+
+func (p *Program) newEvalCtx(opts *Options) *evalCtx {
+	c := &evalCtx{
+		Globals:     p.globals,
+		globalNames: p.globalNames,
+		Output:      opts.Stdout,
+		Stderr:      opts.Stderr,
+		Edits:       opts.Edits,
+
+		allowCommands: opts.AllowCommands,
+	}
+	if c.Output == nil {
+		c.Output = io.Discard
+	}
+	if c.Stderr == nil {
+		c.Stderr = io.Discard
+	}
+	c.Clear()
+	return c
 }
 
-func (c *evalCtx) applyQuery(rootVarName string, qm *sitter.QueryMatch) {
+// lookup resolves a variable by name. Captures are always spelled with their @ prefix
+// and are read-only. A bare name refers to a local or global user variable, falling back
+// to the capture of the same name so that (identifier) @n { print(n) } works without the @.
+func (c *evalCtx) lookup(name string) (Value, bool) {
+	if strings.HasPrefix(name, "@") {
+		v, ok := c.Vars[name]
+		return v, ok
+	}
+	if v, ok := c.Locals[name]; ok {
+		return v, true
+	}
+	if v, ok := c.Globals[name]; ok {
+		return v, true
+	}
+	v, ok := c.Vars["@"+name]
+	return v, ok
+}
+
+// lookupKey resolves a bare dict key like lookup, but without falling back to the capture
+// of the same name, so {name: @name} keeps "name" as the key.
+func (c *evalCtx) lookupKey(name string) (Value, bool) {
+	if strings.HasPrefix(name, "@") {
+		return c.lookup(name)
+	}
+	if v, ok := c.Locals[name]; ok {
+		return v, true
+	}
+	v, ok := c.Globals[name]
+	return v, ok
+}
+
+// applyQuery binds the captures of qm. Captures under a * or + quantifier bind to a list of
+// all the nodes they matched, which is empty if there were none. An optional (?) capture that
+// didn't match is the empty string, like a missing node. It's false if the root capture has no
+// nodes, which can happen when it's quantified, like (comment)* @c.
+func (c *evalCtx) applyQuery(rootVarName string, qm *sitter.QueryMatch) bool {
+	for id := uint32(0); id < c.SQuery.CaptureCount(); id++ {
+		name := "@" + c.SQuery.CaptureNameForId(id)
+		switch c.SQuery.CaptureQuantifierForId(uint32(qm.PatternIndex), id) {
+		case sitter.QuantifierZeroOrMore, sitter.QuantifierOneOrMore:
+			c.Vars[name] = &ListVal{L: []Value{}}
+		case sitter.QuantifierZeroOrOne:
+			c.Vars[name] = &StringVal{}
+		}
+	}
 	for _, capture := range qm.Captures {
 		name := c.SQuery.CaptureNameForId(capture.Index)
 		if name == "" {
 			continue
 		}
-		c.Vars["@"+name] = &NodeVal{N: capture.Node, Src: c.Src}
-		if name == rootVarName[1:] {
-			c.Vars["@"] = &NodeVal{N: capture.Node, Src: c.Src} // store in root variable
+		node := &NodeVal{N: capture.Node, Src: c.Src}
+		if list, ok := c.Vars["@"+name].(*ListVal); ok {
+			list.L = append(list.L, node)
+		} else {
+			c.Vars["@"+name] = node
+		}
+		if _, ok := c.Vars["@"]; !ok && name == rootVarName[1:] {
+			c.Vars["@"] = node // store in root variable, the first node if it's quantified
 		}
 	}
-	if _, ok := c.Vars["@"]; !ok {
-		panic(fmt.Sprintf("root var %s not found, must always be set", rootVarName))
+	_, ok := c.Vars["@"]
+	return ok
+}
+
+// accept binds the captures of m and is true if they pass the checks of q and guard, if any.
+func (p *Program) accept(c *evalCtx, q *query, m *sitter.QueryMatch, guard ast.Expr) (bool, error) {
+	c.SQuery = q.q
+	if !c.applyQuery(q.rootCapture, m) || !c.unify(q.repeated) {
+		return false, nil
+	}
+	matched, err := p.filterPredicates(c, q.preds)
+	if err != nil || !matched {
+		return false, err
+	}
+	if guard != nil {
+		ok, err := p.eval(c, guard)
+		if err != nil {
+			return false, err
+		}
+		return truthy(ok), nil
 	}
+	return true, nil
 }
 
+// Clear resets the captures and local variables before the next match. Globals are kept.
 func (c *evalCtx) Clear() {
 	c.Vars = make(map[string]Value)
+	c.Locals = make(map[string]Value)
 }
 
 func (p *Program) runAction(c *evalCtx, action *ast.Action) error {
 	for _, stmt := range action.Stmts {
-		switch stmt := stmt.(type) {
-		case *ast.Call:
-			return p.runFunc(c, stmt)
+		if err := p.exec(c, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Program) exec(c *evalCtx, stmt ast.Stmt) error {
+	switch stmt := stmt.(type) {
+	case *ast.Call:
+		if name := stmt.FuncName.Name; name == "sub" || name == "gsub" {
+			return p.execSub(c, stmt)
+		}
+		_, err := p.runFunc(c, stmt)
+		return err
+	case *ast.AssignStmt:
+		val, err := p.eval(c, stmt.Rhs)
+		if err != nil {
+			return err
+		}
+		pl, err := p.place(c, stmt.Lhs)
+		if err != nil {
+			return err
+		}
+		switch stmt.Tok {
+		case token.PLUS_EQUAL:
+			val, err = p.update(c, pl, token.PLUS, val)
+		case token.MINUS_EQUAL:
+			val, err = p.update(c, pl, token.MINUS, val)
+		}
+		if err != nil {
+			return err
+		}
+		return p.store(c, pl, val)
+	case *ast.IncDecStmt:
+		op := token.PLUS
+		if stmt.Tok == token.MINUS_MINUS {
+			op = token.MINUS
+		}
+		pl, err := p.place(c, stmt.X)
+		if err != nil {
+			return err
+		}
+		val, err := p.update(c, pl, op, &IntVal{I: 1})
+		if err != nil {
+			return err
+		}
+		return p.store(c, pl, val)
+	case *ast.Block:
+		return p.execBlock(c, stmt)
+	case *ast.IfStmt:
+		cond, err := p.eval(c, stmt.Cond)
+		if err != nil {
+			return err
+		}
+		if truthy(cond) {
+			return p.execBlock(c, stmt.Body)
+		} else if stmt.Else != nil {
+			return p.exec(c, stmt.Else)
+		}
+		return nil
+	case *ast.WhileStmt:
+		for {
+			cond, err := p.eval(c, stmt.Cond)
+			if err != nil {
+				return err
+			}
+			if !truthy(cond) {
+				return nil
+			}
+			if err := p.execBlock(c, stmt.Body); err == errBreak {
+				return nil
+			} else if err != nil && err != errContinue {
+				return err
+			}
+		}
+	case *ast.ForStmt:
+		if stmt.Init != nil {
+			if err := p.exec(c, stmt.Init); err != nil {
+				return err
+			}
+		}
+		for {
+			if stmt.Cond != nil {
+				cond, err := p.eval(c, stmt.Cond)
+				if err != nil {
+					return err
+				}
+				if !truthy(cond) {
+					return nil
+				}
+			}
+			if err := p.execBlock(c, stmt.Body); err == errBreak {
+				return nil
+			} else if err != nil && err != errContinue {
+				return err
+			}
+			if stmt.Post != nil {
+				if err := p.exec(c, stmt.Post); err != nil {
+					return err
+				}
+			}
+		}
+	case *ast.ForInStmt:
+		x, err := p.eval(c, stmt.X)
+		if err != nil {
+			return err
+		}
+		// Iterating a dict binds its keys like AWK, iterating a list binds its elements
+		var keys []Value
+		switch x := x.(type) {
+		case *DictVal:
+			keys = slices.Clone(x.Keys)
+		case *ListVal:
+			keys = slices.Clone(x.L)
 		default:
-			return fmt.Errorf("unexpected statement type %T", stmt)
+			return fmt.Errorf("cannot range over %s (%s)", ast.Format(stmt.X), typeName(x))
+		}
+		for _, key := range keys {
+			if err := p.assign(c, stmt.Key, key); err != nil {
+				return err
+			}
+			if err := p.execBlock(c, stmt.Body); err == errBreak {
+				return nil
+			} else if err != nil && err != errContinue {
+				return err
+			}
+		}
+		return nil
+	case *ast.DeleteStmt:
+		return p.delete(c, stmt.X)
+	case *ast.RedirectStmt:
+		return p.execRedirect(c, stmt)
+	case *ast.GetlineExpr:
+		_, err := p.getline(c, stmt)
+		return err
+	case *ast.ReturnStmt:
+		if stmt.Result == nil {
+			return &returnValue{}
+		}
+		val, err := p.eval(c, stmt.Result)
+		if err != nil {
+			return err
+		}
+		return &returnValue{Value: val}
+	case *ast.BranchStmt:
+		if stmt.Tok == token.BREAK {
+			return errBreak
+		}
+		return errContinue
+	default:
+		return fmt.Errorf("unexpected statement type %T", stmt)
+	}
+}
+
+// errBreak and errContinue unwind the statements of a loop body up to the enclosing loop.
+// The parser guarantees that break and continue only appear inside a loop.
+var (
+	errBreak    = errors.New("break outside of loop")
+	errContinue = errors.New("continue outside of loop")
+)
+
+func (p *Program) execBlock(c *evalCtx, block *ast.Block) error {
+	for _, stmt := range block.Stmts {
+		if err := p.exec(c, stmt); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-func (p *Program) runFunc(c *evalCtx, f *ast.Call) error {
-	switch f.FuncName.Name {
-	case "print":
-		if len(f.Args) != 1 {
-			return fmt.Errorf("print expects 1 argument, got %d", len(f.Args))
+// place is where an assignment writes: a variable, or an entry of a dict or list. It's
+// evaluated once, so that d[f()] += 1 calls f once for both the read and the write.
+type place struct {
+	name      *ast.Ident // or nil for an entry
+	container Value
+	key       Value
+}
+
+func (p *Program) place(c *evalCtx, target ast.Expr) (place, error) {
+	switch target := target.(type) {
+	case *ast.Ident:
+		return place{name: target}, nil
+	case *ast.IndexExpr:
+		container, key, err := p.entry(c, target)
+		return place{container: container, key: key}, err
+	case *ast.SelectorExpr:
+		return p.place(c, selectorIndex(target))
+	default:
+		return place{}, fmt.Errorf("cannot assign to %s", ast.Format(target))
+	}
+}
+
+// update computes the new value of pl for a compound assignment like x += y. Like AWK,
+// a variable that hasn't been assigned yet starts at 0.
+// Entries of a dict are created on first use, so seen[k]++ works without initializing
+// seen[k] (or seen itself) first.
+func (p *Program) update(c *evalCtx, pl place, op token.Type, y Value) (Value, error) {
+	var x Value
+	if pl.name != nil {
+		var ok bool
+		if x, ok = c.lookup(pl.name.Name); !ok {
+			x = &IntVal{I: 0}
+		}
+	} else {
+		var err error // a missing dict entry is "", which is 0 already
+		if x, err = p.index(pl.container, pl.key); err != nil {
+			return nil, err
 		}
-		arg := f.Args[0]
-		val, err := p.eval(c, arg)
+	}
+	return binaryOp(op, x, y)
+}
+
+func (p *Program) assign(c *evalCtx, target ast.Expr, val Value) error {
+	pl, err := p.place(c, target)
+	if err != nil {
+		return err
+	}
+	return p.store(c, pl, val)
+}
+
+func (p *Program) store(c *evalCtx, pl place, val Value) error {
+	if name := pl.name; name != nil {
+		if strings.HasPrefix(name.Name, "@") {
+			return fmt.Errorf("cannot assign to capture %s", name.Name)
+		}
+		if c.globalNames[name.Name] {
+			c.Globals[name.Name] = val
+		} else {
+			c.Locals[name.Name] = val
+		}
+		return nil
+	}
+	if list, ok := pl.container.(*ListVal); ok {
+		i, err := listIndex(list, pl.key)
 		if err != nil {
 			return err
 		}
-		_, err = p.print(c, val)
+		list.L[i] = val
+		return nil
+	}
+	return pl.container.(*DictVal).Set(pl.key, val)
+}
+
+// entry evaluates the dict or list and the key that x[key] refers to when it's assigned.
+func (p *Program) entry(c *evalCtx, x *ast.IndexExpr) (Value, Value, error) {
+	container, err := p.container(c, x.X)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch container.(type) {
+	case *DictVal, *ListVal:
+	default:
+		return nil, nil, fmt.Errorf("cannot assign to %s (%s is not a dict or list)", ast.Format(x), typeName(container))
+	}
+	key, err := p.eval(c, x.Index)
+	if err != nil {
+		return nil, nil, err
+	}
+	return container, key, nil
+}
+
+// container evaluates x as the value that an assignment to x[...] writes into. Like AWK,
+// missing dicts are created on first use, including nested ones like d[a][b] = 1.
+func (p *Program) container(c *evalCtx, x ast.Expr) (Value, error) {
+	switch x := x.(type) {
+	case *ast.Ident:
+		if v, ok := c.lookup(x.Name); ok {
+			return v, nil
+		}
+		dict := NewDict()
+		return dict, p.assign(c, x, dict)
+	case *ast.IndexExpr:
+		parent, key, err := p.entry(c, x)
+		if err != nil {
+			return nil, err
+		}
+		dict, ok := parent.(*DictVal)
+		if !ok {
+			return p.index(parent, key)
+		}
+		v, ok, err := dict.Get(key)
+		if err != nil || ok {
+			return v, err
+		}
+		child := NewDict()
+		return child, dict.Set(key, child)
+	case *ast.SelectorExpr:
+		return p.container(c, selectorIndex(x))
+	default:
+		return p.eval(c, x)
+	}
+}
+
+// delete removes the entry x[key] from its dict, or every entry of x if it's a whole dict.
+func (p *Program) delete(c *evalCtx, x ast.Expr) error {
+	if sel, ok := x.(*ast.SelectorExpr); ok {
+		x = selectorIndex(sel)
+	}
+	if index, ok := x.(*ast.IndexExpr); ok {
+		container, key, err := p.entry(c, index)
 		if err != nil {
 			return err
 		}
+		dict, ok := container.(*DictVal)
+		if !ok {
+			return fmt.Errorf("cannot delete %s (%s is not a dict)", ast.Format(x), typeName(container))
+		}
+		return dict.Delete(key)
 	}
+	v, err := p.eval(c, x)
+	if err != nil {
+		return err
+	}
+	dict, ok := v.(*DictVal)
+	if !ok {
+		return fmt.Errorf("cannot delete %s (%s is not a dict)", ast.Format(x), typeName(v))
+	}
+	dict.Clear()
 	return nil
 }
 
+// runFunc calls f and returns its result, which is nil for functions like print that don't
+// return anything. The number of arguments was already checked by resolveFuncs.
+func (p *Program) runFunc(c *evalCtx, f *ast.Call) (Value, error) {
+	args, err := p.evalArgs(c, f)
+	if err != nil {
+		return nil, err
+	}
+	switch f.FuncName.Name {
+	case "print":
+		return nil, p.print(c, args)
+	case "eprint":
+		ec := *c
+		ec.Output = c.Stderr
+		return nil, p.print(&ec, args)
+	case "close":
+		return p.closeOutput(toString(args[0]))
+	case "system":
+		return p.system(c, toString(args[0]))
+	case "printf":
+		s, err := sprintf(toString(args[0]), args[1:])
+		if err != nil {
+			return nil, fmt.Errorf("printf: %w", err)
+		}
+		_, err = io.WriteString(c.Output, s)
+		return nil, err
+	case "len":
+		return length(args[0]), nil
+	case "append":
+		list, ok := args[0].(*ListVal)
+		if !ok {
+			return nil, fmt.Errorf("append expects a list, got %s", typeName(args[0]))
+		}
+		// Like Go, append returns the new list: l = append(l, x)
+		return &ListVal{L: append(slices.Clone(list.L), args[1:]...)}, nil
+	case "parent", "children", "named_children", "child", "next_sibling", "prev_sibling",
+		"ancestors", "descendants", "enclosing":
+		return navigate(f.FuncName.Name, args)
+	case "length", "substr", "index", "split", "sprintf", "toupper", "tolower", "trim":
+		return stringFunc(f.FuncName.Name, args)
+	case "match":
+		return p.match(args)
+	case "sub", "gsub":
+		return p.sub(c, f.FuncName.Name, args)
+	default:
+		return p.callFunc(c, p.funcs[f.FuncName.Name], args)
+	}
+}
+
+// maxCallDepth limits the recursion of user-defined functions.
+const maxCallDepth = 10000
+
+// callFunc runs the user-defined function f. Its parameters and any variable it assigns are
+// local to the call, so it only shares the global variables with the caller.
+func (p *Program) callFunc(c *evalCtx, f *ast.FuncDecl, args []Value) (Value, error) {
+	if c.depth >= maxCallDepth {
+		return nil, fmt.Errorf("%s: maximum call depth of %d exceeded", f.Name.Name, maxCallDepth)
+	}
+	fc := *c
+	fc.depth++
+	fc.Vars = make(map[string]Value)
+	fc.Locals = make(map[string]Value, len(args))
+	for i, param := range f.Params {
+		fc.Locals[param.Name] = args[i]
+	}
+
+	err := p.execBlock(&fc, f.Body)
+	if ret, ok := err.(*returnValue); ok {
+		return ret.Value, nil
+	}
+	return nil, err
+}
+
+// returnValue unwinds the statements of a function body with its result, like errBreak does
+// for loops.
+type returnValue struct {
+	Value Value // or nil if there is no result
+}
+
+func (r *returnValue) Error() string { return "return outside of function" }
+
+// evalArgs evaluates the arguments of f.
+func (p *Program) evalArgs(c *evalCtx, f *ast.Call) ([]Value, error) {
+	args := make([]Value, len(f.Args))
+	for i, arg := range f.Args {
+		v, err := p.eval(c, arg)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return args, nil
+}
+
+// length is the number of elements of a list or dict and the number of characters of
+// anything else, like a string or a node's text.
+func length(v Value) Value {
+	switch v := v.(type) {
+	case *ListVal:
+		return &IntVal{I: len(v.L)}
+	case *DictVal:
+		return &IntVal{I: len(v.D)}
+	default:
+		return &IntVal{I: utf8.RuneCountInString(toString(v))}
+	}
+}
+
 func (p *Program) eval(c *evalCtx, expr ast.Expr) (Value, error) {
 	switch expr := expr.(type) {
 	case *ast.String:
 		return &StringVal{S: expr.Value}, nil
+	case *ast.Int:
+		i, err := strconv.Atoi(expr.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %s: %w", expr.Value, err)
+		}
+		return &IntVal{I: i}, nil
+	case *ast.Float:
+		f, err := strconv.ParseFloat(expr.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %s: %w", expr.Value, err)
+		}
+		return &FloatVal{F: f}, nil
 	case *ast.Ident:
-		if v, ok := c.Vars[expr.Name]; ok {
+		if v, ok := c.lookup(expr.Name); ok {
 			return v, nil
 		} else {
 			return nil, fmt.Errorf("unknown variable %s", expr.Name)
 		}
+	case *ast.Regex:
+		return &StringVal{S: expr.Value}, nil
+	case *ast.GetlineExpr:
+		return p.getline(c, expr)
 	case *ast.Dict:
 		return p.evalDict(c, expr)
+	case *ast.List:
+		list := &ListVal{L: make([]Value, 0, len(expr.Elts))}
+		for _, elt := range expr.Elts {
+			v, err := p.eval(c, elt)
+			if err != nil {
+				return nil, err
+			}
+			list.L = append(list.L, v)
+		}
+		return list, nil
+	case *ast.ParenExpr:
+		return p.eval(c, expr.X)
+	case *ast.IndexExpr:
+		x, err := p.eval(c, expr.X)
+		if err != nil {
+			return nil, err
+		}
+		index, err := p.eval(c, expr.Index)
+		if err != nil {
+			return nil, err
+		}
+		return p.index(x, index)
+	case *ast.SliceExpr:
+		return p.evalSlice(c, expr)
+	case *ast.SelectorExpr:
+		x, err := p.eval(c, expr.X)
+		if err != nil {
+			return nil, err
+		}
+		return p.selector(x, expr.Sel.Name)
+	case *ast.Call:
+		v, err := p.runFunc(c, expr)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			return nil, fmt.Errorf("%s (no value) used as value", ast.Format(expr))
+		}
+		return v, nil
+	case *ast.UnaryExpr:
+		x, err := p.eval(c, expr.X)
+		if err != nil {
+			return nil, err
+		}
+		return unaryOp(expr.Op, x)
+	case *ast.ConcatExpr:
+		x, err := p.eval(c, expr.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := p.eval(c, expr.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &StringVal{S: toString(x) + toString(y)}, nil
+	case *ast.BinaryExpr:
+		x, err := p.eval(c, expr.X)
+		if err != nil {
+			return nil, err
+		}
+		// && and || short-circuit and only evaluate Y if needed
+		switch expr.Op {
+		case token.AND_AND:
+			if !truthy(x) {
+				return boolVal(false), nil
+			}
+		case token.OR_OR:
+			if truthy(x) {
+				return boolVal(true), nil
+			}
+		}
+		y, err := p.eval(c, expr.Y)
+		if err != nil {
+			return nil, err
+		}
+		switch expr.Op {
+		case token.AND_AND, token.OR_OR:
+			return boolVal(truthy(y)), nil
+		case token.TILDE, token.BANG_TILDE:
+			return p.matchOp(expr.Op, x, y)
+		}
+		return binaryOp(expr.Op, x, y)
 	default:
 		return nil, fmt.Errorf("unexpected expression type %T", expr)
 	}
@@ -198,11 +1020,11 @@ func (p *Program) eval(c *evalCtx, expr ast.Expr) (Value, error) {
 
 func (p *Program) evalDict(c *evalCtx, d *ast.Dict) (Value, error) {
 	var err error
-	m := make(map[Value]Value)
+	dict := NewDict()
 	for _, kv := range d.Entries {
 		var key Value
 		if k, ok := kv.Key.(*ast.Ident); ok {
-			if v, ok := c.Vars[k.Name]; ok {
+			if v, ok := c.lookupKey(k.Name); ok {
 				key = v
 			} else {
 				key = &StringVal{S: k.Name}
@@ -217,30 +1039,29 @@ func (p *Program) evalDict(c *evalCtx, d *ast.Dict) (Value, error) {
 		if err != nil {
 			return nil, err
 		}
-		m[key] = val
+		if err := dict.Set(key, val); err != nil {
+			return nil, err
+		}
 	}
-	return &DictVal{D: m}, nil
+	return dict, nil
 }
 
-func (p *Program) print(c *evalCtx, v Value) (int, error) {
-	switch v := v.(type) {
-	case *StringVal:
-		return fmt.Fprintf(c.Output, "%s\n", v.S)
-	case *IntVal:
-		return fmt.Fprintf(c.Output, "%d\n", v.I)
-	case *NodeVal:
-		if n, err := c.Output.Write(c.Src[v.N.StartByte():v.N.EndByte()]); err != nil {
-			return n, err
-		}
-		return c.Output.Write([]byte{'\n'})
-	case *DictVal:
-		marshalled, err := json.Marshal(v)
-		if err != nil {
-			return 0, err
+// print writes args separated by OFS and followed by ORS. Without any arguments, it prints @.
+func (p *Program) print(c *evalCtx, args []Value) error {
+	if root, ok := c.Vars["@"]; ok && len(args) == 0 {
+		args = []Value{root}
+	}
+	ofs, _ := c.lookup("OFS")
+	ors, _ := c.lookup("ORS")
+
+	var buf strings.Builder
+	for i, arg := range args {
+		if i > 0 {
+			buf.WriteString(toString(ofs))
 		}
-		marshalled = append(marshalled, '\n')
-		return c.Output.Write(marshalled)
-	default:
-		return fmt.Fprintf(c.Output, "<bad:%T>\n", v)
+		buf.WriteString(toString(arg))
 	}
+	buf.WriteString(toString(ors))
+	_, err := io.WriteString(c.Output, buf.String())
+	return err
 }
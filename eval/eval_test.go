@@ -8,6 +8,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/smacker/go-tree-sitter/golang"
 	"github.com/smacker/go-tree-sitter/javascript"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -31,6 +32,804 @@ func TestEvalExample(t *testing.T) {
 	assert.Equal(t, "a\n", stdout.String(), "unexpected output")
 }
 
+func TestEvalActions(t *testing.T) {
+	tests := []struct {
+		name string
+		prog string
+		src  string
+		want string
+	}{
+		{
+			name: "assign",
+			prog: `(identifier) @id {x = @id;print(x)}`,
+			src:  `let a = 10;`,
+			want: "a\n",
+		},
+		{
+			name: "compound assign",
+			prog: `(number) {x = 1;x += 10;x -= 2;x++;x++;x--;print(x)}`,
+			src:  `let a = 10;`,
+			want: "10\n",
+		},
+		{
+			name: "unset variable starts at zero",
+			prog: `(number) {n++;print(n)}`,
+			src:  `f(1, 2);`,
+			want: "1\n1\n",
+		},
+		{
+			name: "bare name falls back to capture",
+			prog: `(identifier) @n {print(n)}`,
+			src:  `foo;`,
+			want: "foo\n",
+		},
+		{
+			name: "user variable shadows capture",
+			prog: `(identifier) @n {n = "local";print(n);print(@n)}`,
+			src:  `foo;`,
+			want: "local\nfoo\n",
+		},
+		{
+			name: "if else",
+			prog: `(identifier) @n {if n == "a" {print("is a")} else if n < "c" {print("before c")} else {print("other")}}`,
+			src:  `a; b; d;`,
+			want: "is a\nbefore c\nother\n",
+		},
+		{
+			name: "while",
+			prog: `(program) {i = 0;while i < 10 {i++;if i == 2 {continue};if i > 3 {break};print(i)}}`,
+			src:  `a;`,
+			want: "1\n3\n",
+		},
+		{
+			name: "for",
+			prog: `(program) {for i = 0; i < 3; i++ {print(i)}}`,
+			src:  `a;`,
+			want: "0\n1\n2\n",
+		},
+		{
+			name: "for in dict",
+			prog: `(program) {for k in {b:1,a:2,c:3} {if k == "b" {continue};print(k)}}`,
+			src:  `a;`,
+			want: "a\nc\n",
+		},
+		{
+			name: "arithmetic",
+			prog: `(program) {print(1 + 2 * 3);print((1 + 2) * 3);print(7 / 2);print(6 / 2);print(7 % 3);print(-2 * 3);print(1.5 + 1);print(1 / 3)}`,
+			src:  `a;`,
+			want: "7\n9\n3.5\n3\n1\n-6\n2.5\n0.333333\n",
+		},
+		{
+			name: "concatenation",
+			prog: `(identifier) @n {print("<" n ">" 1 + 2)}`,
+			src:  `a;`,
+			want: "<a>3\n",
+		},
+		{
+			name: "logical",
+			prog: `(program) {print(1 < 2 && 2 < 1 || 1);print(!0);print(!"x");print(0 && undefined);print(1 || undefined)}`,
+			src:  `a;`,
+			want: "1\n1\n0\n0\n1\n",
+		},
+		{
+			name: "node compares numerically",
+			prog: `(number) @n {if n > 5 {print(n + 1)}}`,
+			src:  `f(3, 10, 7.5);`,
+			want: "11\n8.5\n",
+		},
+		{
+			name: "new line ends statement",
+			prog: "(program) {\n  x = 1\n  y = x\n  print(x y)\n}",
+			src:  `a;`,
+			want: "11\n",
+		},
+		{
+			name: "dict keys compare by value",
+			prog: `(program) {d = {};d[1] = "int";print(d["1"]);d["1"] = "string";print(d);d[1.5] = "x";print(d["1.5"])}`,
+			src:  `a;`,
+			want: "int\n{\"1\":\"string\"}\nx\n",
+		},
+		{
+			name: "node keys compare by text",
+			prog: `(identifier) @n {d = {a: 1};d[@n] += 1;print(d)}`,
+			src:  `a;`,
+			want: "{\"a\":2}\n",
+		},
+		{
+			name: "bare dict keys are not captures",
+			prog: `(identifier) @name {k = "var";print({name: @name, k: 1, @name: 2})}`,
+			src:  `init;`,
+			want: "{\"name\":\"init\",\"var\":1,\"init\":2}\n",
+		},
+		{
+			name: "dict member access",
+			prog: `(program) {d = {a: {b: "x"}};print(d.a.b);print(d["a"]["b"]);d.a.c = 1;d.a.c++;print(d.a);print(d.missing)}`,
+			src:  `a;`,
+			want: "x\nx\n{\"b\":\"x\",\"c\":2}\n\n",
+		},
+		{
+			name: "string index and slice",
+			prog: `(program) {s = "héllo";print(s[1]);print(s[1:3]);print(s[:2]);print(s[3:]);print(s[-1:99])}`,
+			src:  `a;`,
+			want: "é\nél\nhé\nlo\nhéllo\n",
+		},
+		{
+			name: "node fields and children",
+			prog: `(func_decl) @fn {print(@fn.name);print(@fn["name"]);print(fn.parameters[1]);print(@fn.body[0:1]);print(@fn.missing)}`,
+			src:  `function foo(a, b) {}`,
+			want: "foo\nfoo\nb\n{\n\n",
+		},
+		{
+			name: "node properties",
+			prog: `(func_decl name: (id) @name) @fn {print(@name.type);print(@fn.name.text);print(@name.start_line ":" @name.start_col "-" @name.end_line ":" @name.end_col);print(@name.start_byte " " @name.end_byte);print(@name.field);print(@fn.field == "");print(@fn.named);print(@fn.has_error)}`,
+			src:  "\n  function foo() {}",
+			want: "identifier\nfoo\n2:12-2:15\n12 15\nname\n1\n1\n0\n",
+		},
+		{
+			name: "anonymous node properties",
+			prog: `(binary_expression) @b {op = @b.operator;print(op.named);print(op.type);print(op.field);print(@b.has_error)}`,
+			src:  `a + b`,
+			want: "0\n+\noperator\n0\n",
+		},
+		{
+			name: "syntax error",
+			prog: `(program) {print(@.has_error)}`,
+			src:  `a +;`,
+			want: "1\n",
+		},
+		{
+			name: "enclosing function",
+			prog: `(call_expression) @c {fn = enclosing(@c, "function_declaration");print(fn.name " calls " child(@c, "function"))}`,
+			src:  `function outer() { if (x) { foo(1, b) } }`,
+			want: "outer calls foo\n",
+		},
+		{
+			name: "navigation",
+			prog: `(call_expression) @c {
+				print(parent(@c).type)
+				print(children(@c.arguments))
+				print(len(named_children(@c.arguments)))
+				print(child(@c, 0) " " child(@c, 9) "|")
+				args = named_children(@c.arguments)
+				print(next_sibling(args[0]) " " prev_sibling(args[1]) " " next_sibling(args[1]) "|")
+				print(len(ancestors(@c)) " " ancestors(@c)[0].type)
+				print(descendants(@c, "identifier"))
+				print(len(descendants(@c)))
+				print(enclosing(@c, "class_declaration") == "")
+				print(parent(ancestors(@c)[len(ancestors(@c)) - 1]) == "")
+			}`,
+			src:  `foo(1, b);`,
+			want: "expression_statement\n[\"(\",\"1\",\",\",\"b\",\")\"]\n2\nfoo |\nb 1 |\n2 expression_statement\n[\"foo\",\"b\"]\n7\n1\n1\n",
+		},
+		{
+			name: "string builtins",
+			prog: `(program) {
+				s = "héllo wörld"
+				print(length(s) " " length(@))
+				print(substr(s, 2, 3) "|" substr(s, 7) "|" substr(s, 0, 2) "|" substr(s, 20) "|")
+				print(index(s, "wö") " " index(s, "x"))
+				print(split("  a b	c "))
+				print(split("a,b,,c", ","))
+				print(len(split("", ",")))
+				print(toupper(s) " " tolower("ÉA"))
+				print(trim("  x  ") "|" trim("--x-", "-"))
+			}`,
+			src:  `a;`,
+			want: "11 2\néll|wörld|h||\n7 0\n[\"a\",\"b\",\"c\"]\n[\"a\",\"b\",\"\",\"c\"]\n0\nHÉLLO WÖRLD éa\nx|x\n",
+		},
+		{
+			name: "string builtins on nodes",
+			prog: `(identifier) @id {print(toupper(@id) " " substr(@id, 2) " " index(@id, "o"))}`,
+			src:  `foo;`,
+			want: "FOO oo 2\n",
+		},
+		{
+			name: "sprintf",
+			prog: `(program) {print(sprintf("%s=%d %5.2f|%-4s|%q %v %v %x 100%%", "a", "42", 3.14159, "b", @, 1.5, 2, 255))}`,
+			src:  `a;`,
+			want: "a=42  3.14|b   |\"a;\" 1.5 2 ff 100%\n",
+		},
+		{
+			name: "regex match operators",
+			prog: `(identifier) @id {if @id ~ /^[a-z]+$/ {print(@id " lower")} else if @id !~ "_" {print(@id " camel")}}`,
+			src:  `foo; fooBar; foo_bar;`,
+			want: "foo lower\nfooBar camel\n",
+		},
+		{
+			name: "regex builtins",
+			prog: `(program) {
+				print(match("key=value", /(\w+)=(\w+)(;)?/))
+				print(match("x", /y/))
+				print(sub(/o/, "0", "foo") " " gsub(/o/, "0", "foo") " " gsub(/(\w)_(\w)/, "${1}${2}", "a_b c_d"))
+				s = "a.b.c"
+				sub(/\./, "/", s)
+				print(s)
+				gsub("[.]", "/", s)
+				print(s)
+			}`,
+			src:  `a;`,
+			want: "[\"key=value\",\"key\",\"value\",\"\"]\n[]\nf0o f00 ab cd\na/b.c\na/b/c\n",
+		},
+		{
+			name: "print",
+			prog: `(identifier) @id {print(@id, 1, 2.5, [1]);print();OFS = "-";ORS = "|\n";print(@id, @id)}`,
+			src:  `a;`,
+			want: "a 1 2.5 [1]\na\na-a|\n",
+		},
+		{
+			name: "printf",
+			prog: `(identifier) @id {printf("%-5s|%5s|%3d|%6.2f|%q|%v\n", @id, "ab", "7", 3.14159, @id, 1.5)}`,
+			src:  `abc;`,
+			want: "abc  |   ab|  7|  3.14|\"abc\"|1.5\n",
+		},
+		{
+			name: "list literals",
+			prog: `(program) {l = [1, "a", [2.5], {k: @}];print(l);print(len(l));print(l[2][0]);l[1] = "b";print(l[1])}`,
+			src:  `a;`,
+			want: "[1,\"a\",[2.5],{\"k\":\"a;\"}]\n4\n2.5\nb\n",
+		},
+		{
+			name: "list append and slice",
+			prog: `(program) {l = [];l = append(l, 1, 2);m = append(l, 3);print(l);print(m);print(m[1:]);print(len(m[:0]))}`,
+			src:  `a;`,
+			want: "[1,2]\n[1,2,3]\n[2,3]\n0\n",
+		},
+		{
+			name: "list iteration and in",
+			prog: `(program) {for x in ["a", "b"] {print(x)};print(2 in [1, 2]);print("x" in []);if [] {print("empty is true")}}`,
+			src:  `a;`,
+			want: "a\nb\n1\n0\n",
+		},
+		{
+			name: "len",
+			prog: `(program) {print(len("héllo"));print(len({a: 1}));print(len(@))}`,
+			src:  `a;`,
+			want: "5\n1\n2\n",
+		},
+		{
+			name: "where guard",
+			prog: `(identifier) @n where n == "forbidden" && len(n) > 3 {print(n.start_col)}`,
+			src:  `forbidden(x, forbidden, allowed);`,
+			want: "1\n14\n",
+		},
+		{
+			name: "where guard sees every capture",
+			prog: `(call_expression function: (identifier) @fn arguments: (arguments (identifier) @arg)) where fn == arg {print(@)}`,
+			src:  `f(f); f(g); g(g);`,
+			want: "f(f)\ng(g)\n",
+		},
+		{
+			name: "quantified captures bind lists",
+			prog: `(statement_block (expression_statement)+ @stmts) {print(len(stmts));for s in stmts {print(s)}}`,
+			src:  `function f() { a(); b(); }`,
+			want: "2\na();\nb();\n",
+		},
+		{
+			name: "sibling group with anchor",
+			prog: `((comment)+ @docs . (function_declaration) @fn) {print(fn.name, docs[0], len(docs))}`,
+			src:  "// a\n// b\nfunction g() {}\n// c\nlet x;",
+			want: "g // a 2\n",
+		},
+		{
+			name: "missing optional capture is empty",
+			prog: `(call_expression function: (identifier) @fn arguments: (arguments . (string)? @s)) {print(fn, s)}`,
+			src:  `h(); m("s");`,
+			want: "h \nm \"s\"\n",
+		},
+		{
+			name: "predicate on quantified capture checks every node",
+			prog: `((comment)+ @c (#match? @c "^// [ab]"))`,
+			src:  "// a\n// b\nlet x;\n// c\n",
+			want: "// a\n",
+		},
+		{
+			name: "alternation",
+			prog: `[(call_expression) (new_expression)] @c {print(c)}`,
+			src:  `f(); new X(); g;`,
+			want: "f()\nnew X()\n",
+		},
+		{
+			name: "alternation branches are abbreviated",
+			prog: `[(call_exp function: (id) @fn) (new_exp constructor: (id) @fn)] {print(fn)}`,
+			src:  `f(); new X(); g;`,
+			want: "f\nX\n",
+		},
+		{
+			name: "alternation inside pattern",
+			prog: `(binary_expression operator: ["+" "-"] @op) {print(op)}`,
+			src:  `a + b; c * d; e - f;`,
+			want: "+\n-\n",
+		},
+		{
+			name: "negated field",
+			prog: `(variable_declarator name: (identifier) @n !value)`,
+			src:  `let a = 1, b;`,
+			want: "b\n",
+		},
+		{
+			name: "bare wildcard matches anonymous nodes",
+			prog: "(arguments _ @x) {printf(\"%s \", x)}\n(arguments (_) @x) {printf(\"(%s) \", x)}",
+			src:  `f(a, "s");`,
+			want: `( a , "s" ) (a) ("s") `,
+		},
+		{
+			name: "capture anonymous node",
+			prog: "(binary_expression \"!=\" @op) {print(op.type, op.start_col, op.named)}\n\"==\" @eq {print(eq.end_col)}",
+			src:  `x != y; c == d;`,
+			want: "!= 3 0\n13\n",
+		},
+		{
+			name: "anchors on first and last child",
+			prog: "(arguments . (_) @first) {print(first)}\n(arguments (_) @last .) {print(last)}",
+			src:  `g(p, q, r);`,
+			want: "p\nr\n",
+		},
+		{
+			name: "example pattern",
+			prog: "`let @v@ = null` {print(@, v)}",
+			src:  "let v = null;\nconst w = null;\nlet x = 1;",
+			want: "let v = null; v\n",
+		},
+		{
+			name: "example pattern matches the exact shape",
+			prog: "`foo(@x@, \"s\")` @c {print(c, x)}",
+			src:  `foo(a, "s"); foo(a, "t"); foo(a, "s", b); bar(a, "s");`,
+			want: "foo(a, \"s\") a\n",
+		},
+		{
+			name: "example pattern with statements",
+			prog: "`a + 1; b + 2`",
+			src:  `a + 1; b + 2; a + 1; c;`,
+			want: "a + 1;\n",
+		},
+		{
+			name: "example pattern with ellipsis arguments",
+			prog: "`foo(..., @x@, ...)` {print(@, x)}\n`foo(@x@, ...)` {print(x)}\n`foo(...)`",
+			src:  `foo(a); foo(b, c); foo(); bar(a);`,
+			want: "foo(a) a\nfoo(b, c) b\nfoo(b, c) c\na\nb\nfoo(a)\nfoo(b, c)\nfoo()\n",
+		},
+		{
+			name: "example pattern with ellipsis statements",
+			prog: "`if (@c@) { ... return null }` {print(c)}",
+			src:  `if (x) { a(); b(); return null; } if (y) { return null; } if (z) { return 1; }`,
+			want: "x\ny\n",
+		},
+		{
+			name: "example pattern with spread",
+			prog: "`foo(...@x@)` {print(x)}\n`foo(..., ...@x@)` {print(@)}",
+			src:  `foo(...args); foo(a, ...b); foo(a);`,
+			want: "args\nfoo(...args)\nfoo(a, ...b)\n",
+		},
+		{
+			name: "repeated capture matches the same code",
+			prog: `(binary_expression left: (_) @a right: (_) @a) {print(a)}`,
+			src:  `x == x; x == y; f(a /* c */, b) == f(a,b); f(a) == f(b);`,
+			want: "x\nf(a /* c */, b)\n",
+		},
+		{
+			name: "repeated hole in example pattern",
+			prog: "`@x@ = @x@`",
+			src:  `a = a; a = b; o.p = o.p; o.p = o.q;`,
+			want: "a = a\no.p = o.p\n",
+		},
+		{
+			name: "inside constraint with guard",
+			prog: `(call_expression) @c inside (function_declaration name: (identifier) @f where f == "init") {print(c, f)}`,
+			src:  `function init() { setup(); } function other() { risky(); }`,
+			want: "setup() init\n",
+		},
+		{
+			name: "not inside constraint",
+			prog: `(call_expression) @c not inside (try_statement)`,
+			src:  `try { a(); } catch (e) {} b(); try { if (x) { c(); } } finally {}`,
+			want: "b()\n",
+		},
+		{
+			name: "has and not has constraints",
+			prog: "(call_expression) @c has (call_expression)\n(call_expression) not has (call_expression) not inside (call_expression)",
+			src:  `f(g(h())); i();`,
+			want: "f(g(h()))\ng(h())\ni()\n",
+		},
+		{
+			name: "constraint captures unify with the rule",
+			prog: "`@fn@()` inside `function @fn@() { ... }`",
+			src:  `function f() { g(); } function g() { h(); g(); }`,
+			want: "g()\n",
+		},
+		{
+			name: "quantified root skips empty matches",
+			prog: "(comment)* @c {print(c)}\n(comment)? @d {print(d)}",
+			src:  "// a\nfoo();",
+			want: "[\"// a\"]\n// a\n",
+		},
+		{
+			name: "pattern without action prints the match",
+			prog: "(number) where @ > 1\n(string)",
+			src:  `f(1, 2, "a", 3);`,
+			want: "2\n3\n\"a\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog, err := Compile("<test>", []byte(tt.prog))
+			require.NoError(t, err)
+
+			var stdout bytes.Buffer
+			err = prog.Eval(context.Background(), []byte(tt.src), &Options{
+				Language: javascript.GetLanguage(),
+				Stdout:   &stdout,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, stdout.String())
+		})
+	}
+}
+
+func TestPredicates(t *testing.T) {
+	tests := []struct {
+		prog string
+		want string
+	}{
+		{`((identifier) @id (#match? @id "^[A-Z]")) {print(@id)}`, "Bar\n"},
+		{`((identifier) @id (#not-match? @id "^[A-Z]"))`, "foo\nbaz\nbaz\n"},
+		{`((identifier) @id (#eq? @id "baz")) {print(@)}`, "baz\nbaz\n"},
+		{`((identifier) @id (#not-eq? @id "baz"))`, "foo\nBar\n"},
+		{`((identifier) @id (#any-of? @id "foo" "baz" "qux"))`, "foo\nbaz\nbaz\n"},
+		{`((identifier) @id (#not-any-of? @id "foo" "baz"))`, "Bar\n"},
+		{`(call_expression function: (identifier) @fn arguments: (arguments (identifier) @arg (#eq? @fn @arg)))`, "baz(baz)\n"},
+		{`((identifier) @id (#match? @id "^b") (#not-eq? @id "bar")) where len(@id) > 2`, "baz\nbaz\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.prog, func(t *testing.T) {
+			prog, err := Compile("<test>", []byte(tt.prog))
+			require.NoError(t, err)
+
+			var stdout bytes.Buffer
+			err = prog.Eval(context.Background(), []byte(`foo(Bar); baz(baz);`), &Options{
+				Language: javascript.GetLanguage(),
+				Stdout:   &stdout,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, stdout.String())
+		})
+	}
+
+	errors := []struct {
+		prog string
+		want string
+	}{
+		{`((id) @id (#matches? @id "a"))`, "<test>:1:11: unknown predicate #matches?"},
+		{`((id) @id (#match? @id "("))`, "#match?: error parsing regexp"},
+		{`((id) @id (#match? @id @id))`, "#match?: wanted a capture and a regular expression"},
+		{`((id) @id (#eq? "a" @id))`, "#eq?: first argument must be a capture"},
+		{`((id) @id (#eq? @id))`, "#eq?: wanted 2 arguments, got 1"},
+		{`((id) @id (#any-of? @id "a" @id))`, "#any-of?: wanted a capture and a list of strings"},
+	}
+	for _, tt := range errors {
+		t.Run(tt.prog, func(t *testing.T) {
+			_, err := Compile("<test>", []byte(tt.prog))
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+func TestExamplePatternErrors(t *testing.T) {
+	prog, err := Compile("<test>", []byte("`let = =` {print(@)}"))
+	require.NoError(t, err)
+	err = prog.Eval(context.Background(), []byte(`let a = 1;`), &Options{Language: javascript.GetLanguage()})
+	assert.ErrorContains(t, err, "example pattern `let = =` is not valid code")
+
+	prog, err = Compile("<test>", []byte("`...`"))
+	require.NoError(t, err)
+	err = prog.Eval(context.Background(), []byte(`let a = 1;`), &Options{Language: javascript.GetLanguage()})
+	assert.ErrorContains(t, err, "example pattern `...` has nothing to match")
+}
+
+func TestExamplePatternVariadic(t *testing.T) {
+	prog, err := Compile("<test>", []byte("`g(@x@...)` {print(x)}\n`g(...)`"))
+	require.NoError(t, err)
+
+	var stdout bytes.Buffer
+	err = prog.Eval(context.Background(), []byte("package p\nfunc f() { g(xs...); g(x) }"), &Options{
+		Language: golang.GetLanguage(),
+		Stdout:   &stdout,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "xs\ng(xs...)\ng(x)\n", stdout.String())
+}
+
+func TestBeginEnd(t *testing.T) {
+	prog, err := Compile("<test>", []byte(`
+BEGIN { print("begin") }
+BEGINFILE { print("file " @) }
+(identifier) @id { print(@id) }
+ENDFILE { print("end file") }
+END { print("end") }
+`))
+	require.NoError(t, err)
+	assert.True(t, prog.ReadsInput())
+
+	var stdout bytes.Buffer
+	opts := &Options{Language: javascript.GetLanguage(), Stdout: &stdout}
+	ctx := context.Background()
+	require.NoError(t, prog.Begin(ctx, opts))
+	require.NoError(t, prog.Eval(ctx, []byte(`a;`), opts))
+	require.NoError(t, prog.Eval(ctx, []byte(`b;`), opts))
+	require.NoError(t, prog.End(ctx, opts))
+	assert.Equal(t, "begin\nfile a;\na\nend file\nfile b;\nb\nend file\nend\n", stdout.String())
+}
+
+func TestGlobals(t *testing.T) {
+	prog, err := Compile("<test>", []byte(`
+BEGIN { total = 0; names = "" }
+BEGINFILE { perFile = 0 }
+(identifier) @id { total++; perFile++; local++; names = names @id }
+(number) { total += 10 }
+ENDFILE { print(perFile) }
+END { print(total); print(names) }
+`))
+	require.NoError(t, err)
+
+	var stdout bytes.Buffer
+	opts := &Options{Language: javascript.GetLanguage(), Stdout: &stdout}
+	ctx := context.Background()
+	require.NoError(t, prog.Begin(ctx, opts))
+	require.NoError(t, prog.Eval(ctx, []byte(`a; b;`), opts))
+	require.NoError(t, prog.Eval(ctx, []byte(`c = 1;`), opts))
+	require.NoError(t, prog.End(ctx, opts))
+	assert.Equal(t, "2\n1\n13\nabc\n", stdout.String())
+}
+
+func TestFuncs(t *testing.T) {
+	prog, err := Compile("<test>", []byte(`
+func fib(n) {
+	if n < 2 { return n }
+	return fib(n - 1) + fib(n - 2)
+}
+func count(name) {
+	calls++
+	seen = name // local to the call
+	names[calls] = name
+}
+func nothing() { return }
+BEGIN { calls = 0; names = {} }
+(identifier) @id { count(@id); print(fib(len(names) + 5)) }
+END { print(calls); print(names); print(fib(20)) }
+`))
+	require.NoError(t, err)
+
+	var stdout bytes.Buffer
+	opts := &Options{Language: javascript.GetLanguage(), Stdout: &stdout}
+	ctx := context.Background()
+	require.NoError(t, prog.Begin(ctx, opts))
+	require.NoError(t, prog.Eval(ctx, []byte(`a; b;`), opts))
+	require.NoError(t, prog.End(ctx, opts))
+	assert.Equal(t, "8\n13\n2\n{\"1\":\"a\",\"2\":\"b\"}\n6765\n", stdout.String())
+}
+
+func TestCompoundAssignEvaluatesIndexOnce(t *testing.T) {
+	prog, err := Compile("<test>", []byte(`
+func next() { i++; return i }
+BEGIN { i = 0 }
+(program) { d = {}; d[next()] += 5; print(d, i); l = [1, 2]; l[next() - 2]++; print(l, i) }
+`))
+	require.NoError(t, err)
+
+	var stdout bytes.Buffer
+	opts := &Options{Language: javascript.GetLanguage(), Stdout: &stdout}
+	ctx := context.Background()
+	require.NoError(t, prog.Begin(ctx, opts))
+	require.NoError(t, prog.Eval(ctx, []byte(`a;`), opts))
+	assert.Equal(t, "{\"1\":5} 1\n[2,2] 2\n", stdout.String())
+}
+
+func TestFuncErrors(t *testing.T) {
+	tests := []struct {
+		prog string
+		want string
+	}{
+		{`(id) { foo(1) }`, "<test>:1:8: unknown function foo"},
+		{`func f(a) {} (id) { f() }`, "f expects 1 arguments, got 0"},
+		{`(id) { print(len()) }`, "len expects 1 arguments, got 0"},
+		{`func f() {} func f() {}`, "function f redeclared"},
+		{`func print(x) {}`, "cannot redeclare builtin function print"},
+		{`(id) { x = "a" ~ /(/ }`, "<test>:1:18: error parsing regexp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.prog, func(t *testing.T) {
+			_, err := Compile("<test>", []byte(tt.prog))
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+
+	run := func(src string) error {
+		prog, err := Compile("<test>", []byte(src))
+		require.NoError(t, err)
+		return prog.Begin(context.Background(), &Options{})
+	}
+	assert.ErrorContains(t, run(`func f() { return } BEGIN { x = f() }`), "f() (no value) used as value")
+	assert.NoError(t, run(`func f() { return x } BEGIN { x = 1; f() }`))
+	assert.ErrorContains(t, run(`func f(n) { return y } BEGIN { f(1) }`), "unknown variable y")
+	assert.ErrorContains(t, run(`func f(n) { return f(n + 1) } BEGIN { f(1) }`), "maximum call depth")
+	assert.ErrorContains(t, run(`BEGIN { x = parent("a") }`), "parent expects a node, got string")
+	assert.ErrorContains(t, run(`BEGIN { x = sprintf("%d %d", 1) }`), "sprintf: not enough arguments")
+	assert.ErrorContains(t, run(`BEGIN { printf("%d %z", 1, 2) }`), "printf: unknown verb %z")
+	assert.ErrorContains(t, run(`BEGIN { x = match("a", "(") }`), "missing closing )")
+	assert.ErrorContains(t, run(`BEGIN { sub(/a/, "b") }`), "sub: there is no @ to rewrite")
+	assert.ErrorContains(t, run(`BEGIN { x = sprintf("%d", "a") }`), `cannot convert "a" to a number`)
+}
+
+func TestEdits(t *testing.T) {
+	prog, err := Compile("<test>", []byte(`
+(identifier) @id { if @id ~ /_/ { gsub(/_(\w)/, "$1", @id) } }
+(string) { sub(/"/, "'") }
+(number) @n { x = sub(/1/, "2", @n) }
+`))
+	require.NoError(t, err)
+
+	src := []byte(`foo_bar = "a" + 1 + snake_case_name;`)
+	var edits []Edit
+	err = prog.Eval(context.Background(), src, &Options{Language: javascript.GetLanguage(), Edits: &edits})
+	require.NoError(t, err)
+	assert.Len(t, edits, 3, "only statements make edits")
+
+	out, err := ApplyEdits(src, edits)
+	require.NoError(t, err)
+	assert.Equal(t, `foobar = 'a" + 1 + snakecasename;`, string(out))
+
+	_, err = ApplyEdits(src, []Edit{{Start: 0, End: 3}, {Start: 2, End: 4}})
+	assert.ErrorContains(t, err, "edit of bytes 2-4 overlaps edit of bytes 0-3")
+}
+
+func TestOutputVars(t *testing.T) {
+	prog, err := Compile("<test>", []byte(`
+BEGIN { OFS = "\t"; ORS = ";" }
+(identifier) @id { print(@id.type, @id) }
+END { ORS = "\n"; print(); printf("%d%%\n", 100) }
+`))
+	require.NoError(t, err)
+
+	var stdout bytes.Buffer
+	opts := &Options{Language: javascript.GetLanguage(), Stdout: &stdout}
+	ctx := context.Background()
+	require.NoError(t, prog.Begin(ctx, opts))
+	require.NoError(t, prog.Eval(ctx, []byte(`a; b;`), opts))
+	require.NoError(t, prog.End(ctx, opts))
+	assert.Equal(t, "identifier\ta;identifier\tb;\n100%\n", stdout.String())
+}
+
+func TestRedirect(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "log.txt"), []byte("old\n"), 0644))
+	prog, err := Compile("<test>", []byte(`
+BEGIN { dir = "`+dir+`/" }
+(identifier) @id {
+	print(@id) > dir "ids.txt"
+	printf("%s;", @id) >> dir "log.txt"
+	eprint("found", @id)
+}
+END {
+	print(close(dir "ids.txt"), close(dir "missing"))
+	print("again") > dir "ids.txt"
+}
+`))
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	opts := &Options{Language: javascript.GetLanguage(), Stdout: &stdout, Stderr: &stderr}
+	ctx := context.Background()
+	require.NoError(t, prog.Begin(ctx, opts))
+	require.NoError(t, prog.Eval(ctx, []byte(`a; b;`), opts))
+	require.NoError(t, prog.Eval(ctx, []byte(`c;`), opts))
+	require.NoError(t, prog.End(ctx, opts))
+	require.NoError(t, prog.Close())
+
+	assert.Equal(t, "0 -1\n", stdout.String())
+	assert.Equal(t, "found a\nfound b\nfound c\n", stderr.String())
+	ids, err := os.ReadFile(filepath.Join(dir, "ids.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "again\n", string(ids), "reopening after close truncates")
+	log, err := os.ReadFile(filepath.Join(dir, "log.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "old\na;b;c;", string(log))
+}
+
+func TestCommands(t *testing.T) {
+	prog, err := Compile("<test>", []byte(`
+BEGIN { n = 0 }
+(identifier) @id { print(@id) | "sort -r" }
+END {
+	print(close("sort -r"))
+	while ("printf 'x\ny\n'" | getline line) > 0 { n++; last = line }
+	print(n, last)
+	print(system("echo from system; exit 3"))
+	"echo once" | getline first
+	print(first)
+}
+`))
+	require.NoError(t, err)
+
+	var stdout bytes.Buffer
+	opts := &Options{Language: javascript.GetLanguage(), Stdout: &stdout, AllowCommands: true}
+	ctx := context.Background()
+	require.NoError(t, prog.Begin(ctx, opts))
+	require.NoError(t, prog.Eval(ctx, []byte(`b; c; a;`), opts))
+	require.NoError(t, prog.End(ctx, opts))
+	require.NoError(t, prog.Close())
+	assert.Equal(t, "c\nb\na\n0\n2 y\nfrom system\n3\nonce\n", stdout.String())
+}
+
+func TestCommandsNotAllowed(t *testing.T) {
+	for _, src := range []string{
+		`BEGIN { system("echo") }`,
+		`BEGIN { "echo" | getline x }`,
+		`BEGIN { print("x") | "cat" }`,
+	} {
+		prog, err := Compile("<test>", []byte(src))
+		require.NoError(t, err)
+		err = prog.Begin(context.Background(), &Options{})
+		assert.ErrorContains(t, err, "running commands is not allowed", src)
+	}
+}
+
+func TestLocalsDoNotLeak(t *testing.T) {
+	// n is only read in END, which is enough to share it, but local isn't shared
+	prog, err := Compile("<test>", []byte(`(identifier) { n++; local++; print(local) } END { print(n) }`))
+	require.NoError(t, err)
+
+	var stdout bytes.Buffer
+	opts := &Options{Language: javascript.GetLanguage(), Stdout: &stdout}
+	ctx := context.Background()
+	require.NoError(t, prog.Begin(ctx, opts))
+	require.NoError(t, prog.Eval(ctx, []byte(`a; b;`), opts))
+	require.NoError(t, prog.End(ctx, opts))
+	assert.Equal(t, "1\n1\n2\n", stdout.String())
+}
+
+func TestAssociativeArrays(t *testing.T) {
+	prog, err := Compile("<test>", []byte(`
+BEGIN { seen = {} }
+(identifier) @n { seen[@n]++; nested[@n]["x"] = 1 }
+END {
+	for k in seen { print(k " " seen[k]) }
+	print(seen)
+	print("a" in seen)
+	print("z" in seen)
+	print(seen["z"] + 1)
+	print("z" in seen)
+	delete seen["a"]
+	print(seen)
+	delete seen
+	print(seen)
+}
+`))
+	require.NoError(t, err)
+
+	var stdout bytes.Buffer
+	opts := &Options{Language: javascript.GetLanguage(), Stdout: &stdout}
+	ctx := context.Background()
+	require.NoError(t, prog.Begin(ctx, opts))
+	require.NoError(t, prog.Eval(ctx, []byte(`a; b; a;`), opts))
+	require.NoError(t, prog.End(ctx, opts))
+	assert.Equal(t, `a 2
+b 1
+{"a":2,"b":1}
+1
+0
+1
+0
+{"b":1}
+{}
+`, stdout.String())
+}
+
+func TestReadsInput(t *testing.T) {
+	prog, err := Compile("<test>", []byte(`BEGIN { print(1 + 2) }`))
+	require.NoError(t, err)
+	assert.False(t, prog.ReadsInput())
+}
+
 func TestUseCases(t *testing.T) {
 	tests := []struct {
 		filename string
@@ -38,6 +837,7 @@ func TestUseCases(t *testing.T) {
 		{"testdata/JavaBasic/Example.java"},
 		{"testdata/Abbrev/test.py"},
 		{"testdata/Unknown/test.go"},
+		{"testdata/Example/test.go"},
 	}
 
 	for _, tt := range tests {
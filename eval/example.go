@@ -0,0 +1,237 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/masp/awktree/ast"
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// Example patterns like `let @v@ = null` are parsed with the target language and compiled
+// into the equivalent query, so rules can be written without knowing the grammar's node
+// names. Every child is anchored so that the match has exactly the same shape, and leaves
+// like identifiers must have the same text. An ellipsis (...) removes the anchor, so any
+// number of arguments, statements or elements can be in its place.
+
+var holeRe = regexp.MustCompile(`@([a-zA-Z_][a-zA-Z_0-9]*)@`)
+
+// holePrefix replaces a hole @name@ with an identifier, which is valid in most places of
+// most languages. An ellipsis is replaced with one too.
+const (
+	holePrefix = "__hole_"
+	ellipsis   = "__ellipsis"
+)
+
+// exampleWrappers are tried in order until the snippet parses without errors, since most
+// snippets aren't a whole file in languages like Go or Java. %s is the snippet.
+var exampleWrappers = []string{
+	"%s",
+	"package p\nfunc _() {\n%s\n}",
+	"package p\n%s",
+	"class C {\nvoid m() {\n%s\n}\n}",
+	"class C {\n%s\n}",
+}
+
+// replaceEllipses replaces every ... that stands alone as an element, like in foo(..., x) or
+// { ... return nil }, with an identifier. Others are the language's own, like the spread in
+// foo(...args) or the variadic call g(x...).
+func replaceEllipses(snippet string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(snippet, "...")
+		if i < 0 {
+			b.WriteString(snippet)
+			return b.String()
+		}
+		b.WriteString(snippet[:i])
+		out, after := b.String(), snippet[i+3:]
+		before := out[max(len(out)-1, 0):]
+		if before != "" && !strings.ContainsAny(before, "([{,; \t\r\n") ||
+			after != "" && !strings.ContainsAny(after[:1], ",;)]} \t\r\n") {
+			b.WriteString("...")
+			snippet = after
+			continue
+		}
+		b.WriteString(ellipsis)
+		next := strings.TrimLeft(after, " \t\r\n")
+		space := after[:len(after)-len(next)]
+		if next != "" && !strings.ContainsAny(next[:1], ",;)]}") && !strings.Contains(space, "\n") {
+			b.WriteString("\n") // the next statement has to start on a new line
+		}
+		snippet = after
+	}
+}
+
+type exampleCompiler struct {
+	src   []byte
+	holes map[string]string // identifier in src -> capture name
+	preds []ast.Node        // the predicates on the text of the leaves
+}
+
+// compileExample parses ex with the language and compiles it into a group of patterns, one
+// for each top-level node in the snippet.
+func (l *language) compileExample(ex *ast.ExamplePattern) (ast.Pattern, error) {
+	holes := make(map[string]string)
+	snippet := holeRe.ReplaceAllStringFunc(strings.TrimSpace(ex.Value), func(hole string) string {
+		name := hole[1 : len(hole)-1]
+		holes[holePrefix+name] = "@" + name
+		return holePrefix + name
+	})
+	snippet = replaceEllipses(snippet)
+	for _, wrapper := range exampleWrappers {
+		prefix, _, _ := strings.Cut(wrapper, "%s")
+		src := []byte(strings.Replace(wrapper, "%s", snippet, 1))
+		root, err := sitter.ParseCtx(context.Background(), src, l.lang)
+		if err != nil {
+			return nil, err
+		}
+		if root.HasError() {
+			continue
+		}
+		c := &exampleCompiler{src: src, holes: holes}
+		nodes := snippetNodes(root, uint32(len(prefix)), uint32(len(prefix)+len(snippet)))
+		group := &ast.QueryPattern{Capture: ex.Capture}
+		group.Args = c.compileChildren(nodes, make([]string, len(nodes)), false)
+		if len(group.Args) == 0 {
+			return nil, fmt.Errorf("example pattern `%s` has nothing to match", ex.Value)
+		}
+		group.Args = append(group.Args, c.preds...)
+		return group, nil
+	}
+	return nil, fmt.Errorf("example pattern `%s` is not valid code", ex.Value)
+}
+
+// snippetNodes finds the nodes that make up the snippet between start and end: the deepest
+// node that spans exactly that range, or the children of the deepest node that contains it.
+// The root is never used, it would only match whole files.
+func snippetNodes(root *sitter.Node, start, end uint32) []*sitter.Node {
+	n, isRoot := root, true
+descend:
+	for {
+		for i := 0; i < int(n.ChildCount()); i++ {
+			child := n.Child(i)
+			if child.StartByte() <= start && end <= child.EndByte() {
+				n, isRoot = child, false
+				continue descend
+			}
+		}
+		break
+	}
+	if !isRoot && n.StartByte() == start && n.EndByte() == end {
+		return []*sitter.Node{n}
+	}
+	var nodes []*sitter.Node
+	for i := 0; i < int(n.ChildCount()); i++ {
+		child := n.Child(i)
+		if start <= child.StartByte() && child.EndByte() <= end && !child.IsExtra() {
+			nodes = append(nodes, child)
+		}
+	}
+	return nodes
+}
+
+func (c *exampleCompiler) compile(n *sitter.Node) ast.Node {
+	text := n.Content(c.src)
+	if name, ok := c.holes[text]; ok {
+		return &ast.QueryPattern{Symbol: &ast.Ident{Name: "_"}, Capture: &ast.Ident{Name: name}}
+	}
+	if !n.IsNamed() {
+		return &ast.AnonPattern{Name: &ast.String{Value: quoteQuery(n.Type())}}
+	}
+
+	pattern := &ast.QueryPattern{Symbol: &ast.Ident{Name: n.Type()}}
+	var children []*sitter.Node
+	var fields []string
+	for i := 0; i < int(n.ChildCount()); i++ {
+		if child := n.Child(i); !child.IsExtra() {
+			children = append(children, child)
+			fields = append(fields, n.FieldNameForChild(i))
+		}
+	}
+	if len(children) > 0 {
+		pattern.Args = c.compileChildren(children, fields, true)
+		return pattern
+	}
+
+	// A leaf like an identifier or a number must have the same text
+	capture := &ast.Ident{Name: fmt.Sprintf("@__text%d", len(c.preds))}
+	pattern.Capture = capture
+	c.preds = append(c.preds, &ast.QueryPredicate{
+		Name: &ast.Ident{Name: "#eq?"},
+		Args: []ast.Expr{capture, &ast.String{Value: quoteQuery(text)}},
+	})
+	return pattern
+}
+
+// compileChildren compiles a sequence of sibling nodes, anchored next to each other unless
+// there's an ellipsis between them. If anchorEnds, the first and last are anchored to the
+// start and end of their parent too.
+func (c *exampleCompiler) compileChildren(children []*sitter.Node, fields []string, anchorEnds bool) (args []ast.Node) {
+	// An ellipsis takes a separator with it, so foo(..., x) matches foo(x) too.
+	skip := make([]bool, len(children))
+	for i, child := range children {
+		if !c.isEllipsis(child) {
+			continue
+		}
+		skip[i] = true
+		if i+1 < len(children) && isSeparator(children[i+1]) {
+			skip[i+1] = true
+		} else if i > 0 && isSeparator(children[i-1]) {
+			skip[i-1] = true
+		}
+	}
+
+	anchor := anchorEnds
+	for i, child := range children {
+		if c.isEllipsis(child) {
+			anchor = false
+		}
+		if skip[i] {
+			continue
+		}
+		if anchor {
+			args = append(args, &ast.Anchor{})
+		}
+		if fields[i] != "" {
+			args = append(args, &ast.PatternField{Name: &ast.Ident{Name: fields[i]}})
+		}
+		args = append(args, c.compile(child))
+		anchor = true
+	}
+	if anchor && anchorEnds {
+		args = append(args, &ast.Anchor{})
+	}
+	return args
+}
+
+func (c *exampleCompiler) isEllipsis(n *sitter.Node) bool {
+	return n.Content(c.src) == ellipsis
+}
+
+// isSeparator is true for the anonymous nodes between arguments, statements and elements.
+func isSeparator(n *sitter.Node) bool {
+	if n.IsNamed() {
+		return false
+	}
+	switch n.Type() {
+	case ",", ";", "\n":
+		return true
+	}
+	return false
+}
+
+var queryEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+var queryUnescaper = strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\n`, "\n", `\r`, "\r", `\t`, "\t", `\0`, "\x00")
+
+// quoteQuery escapes s to be the contents of a string in a query.
+func quoteQuery(s string) string {
+	return queryEscaper.Replace(s)
+}
+
+// unquoteQuery is the value of the contents of a string in a query, like tree-sitter reads it.
+func unquoteQuery(s string) string {
+	return queryUnescaper.Replace(s)
+}
@@ -0,0 +1,103 @@
+package eval
+
+import (
+	"fmt"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// The navigation builtins move from a node to the nodes around it. A node that doesn't exist,
+// like the parent of the root, is the empty value so that it can be checked with if.
+// children and child(n, i) count anonymous nodes like "," too, but next_sibling and
+// prev_sibling only return named siblings, skipping the punctuation between them.
+
+// navigate calls the navigation builtin name on args, which the caller has already checked
+// the number of.
+func navigate(name string, args []Value) (Value, error) {
+	n, ok := args[0].(*NodeVal)
+	if !ok {
+		return nil, fmt.Errorf("%s expects a node, got %s", name, typeName(args[0]))
+	}
+	switch name {
+	case "parent":
+		return n.wrap(n.N.Parent()), nil
+	case "children":
+		return n.children(int(n.N.ChildCount()), n.N.Child), nil
+	case "named_children":
+		return n.children(int(n.N.NamedChildCount()), n.N.NamedChild), nil
+	case "child":
+		// child(n, "field") is the child for a field, child(n, i) the i-th child
+		if field, ok := args[1].(*StringVal); ok {
+			return n.field(field.S), nil
+		}
+		i, err := toInt(args[1])
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= int(n.N.ChildCount()) {
+			return &StringVal{}, nil
+		}
+		return n.wrap(n.N.Child(i)), nil
+	case "next_sibling":
+		return n.wrap(n.N.NextNamedSibling()), nil
+	case "prev_sibling":
+		return n.wrap(n.N.PrevNamedSibling()), nil
+	case "ancestors":
+		list := &ListVal{}
+		for p := n.N.Parent(); p != nil; p = p.Parent() {
+			list.L = append(list.L, n.wrap(p))
+		}
+		return list, nil
+	case "descendants":
+		typ := ""
+		if len(args) > 1 {
+			typ = toString(args[1])
+		}
+		list := &ListVal{}
+		n.walk(func(d *sitter.Node) {
+			if typ == "" || d.Type() == typ {
+				list.L = append(list.L, n.wrap(d))
+			}
+		})
+		return list, nil
+	case "enclosing":
+		typ := toString(args[1])
+		for p := n.N.Parent(); p != nil; p = p.Parent() {
+			if p.Type() == typ {
+				return n.wrap(p), nil
+			}
+		}
+		return &StringVal{}, nil
+	default:
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+}
+
+// wrap makes a value for node m from the same tree as n, or the empty value if m is nil.
+func (n *NodeVal) wrap(m *sitter.Node) Value {
+	if m == nil {
+		return &StringVal{}
+	}
+	return &NodeVal{N: m, Src: n.Src}
+}
+
+func (n *NodeVal) children(count int, child func(int) *sitter.Node) *ListVal {
+	list := &ListVal{L: make([]Value, 0, count)}
+	for i := 0; i < count; i++ {
+		list.L = append(list.L, n.wrap(child(i)))
+	}
+	return list
+}
+
+// walk calls f for every descendant of n (but not n itself) in source order.
+func (n *NodeVal) walk(f func(*sitter.Node)) {
+	var visit func(m *sitter.Node)
+	visit = func(m *sitter.Node) {
+		for i := 0; i < int(m.ChildCount()); i++ {
+			child := m.Child(i)
+			f(child)
+			visit(child)
+		}
+	}
+	visit(n.N)
+}
@@ -0,0 +1,265 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/masp/awktree/token"
+)
+
+// binaryOp applies the operator op to x and y.
+func binaryOp(op token.Type, x, y Value) (Value, error) {
+	switch op {
+	case token.EQUAL_EQUAL, token.BANG_EQUAL, token.LESS, token.LESS_EQUAL, token.GREATER, token.GREATER_EQUAL:
+		return compare(op, x, y), nil
+	case token.IN:
+		switch y := y.(type) {
+		case *DictVal:
+			_, found, err := y.Get(x)
+			return boolVal(found), err
+		case *ListVal:
+			// Elements are compared like ==, so "1" in [1] holds
+			for _, elt := range y.L {
+				if truthy(compare(token.EQUAL_EQUAL, x, elt)) {
+					return boolVal(true), nil
+				}
+			}
+			return boolVal(false), nil
+		default:
+			return nil, fmt.Errorf("invalid operation: in needs a dict or list, got %s", typeName(y))
+		}
+	}
+
+	xn, err := toNumber(x)
+	if err != nil {
+		return nil, fmt.Errorf("invalid operation: operator %s not defined on %s: %w", op.Op(), typeName(x), err)
+	}
+	yn, err := toNumber(y)
+	if err != nil {
+		return nil, fmt.Errorf("invalid operation: operator %s not defined on %s: %w", op.Op(), typeName(y), err)
+	}
+
+	xi, xok := xn.(*IntVal)
+	yi, yok := yn.(*IntVal)
+	if xok && yok {
+		switch op {
+		case token.PLUS:
+			return &IntVal{I: xi.I + yi.I}, nil
+		case token.MINUS:
+			return &IntVal{I: xi.I - yi.I}, nil
+		case token.STAR:
+			return &IntVal{I: xi.I * yi.I}, nil
+		case token.PERCENT:
+			if yi.I == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			return &IntVal{I: xi.I % yi.I}, nil
+		}
+		// Division always produces a float so that ratios like a / b aren't truncated
+	}
+
+	xf, yf := toFloat(xn), toFloat(yn)
+	switch op {
+	case token.PLUS:
+		return &FloatVal{F: xf + yf}, nil
+	case token.MINUS:
+		return &FloatVal{F: xf - yf}, nil
+	case token.STAR:
+		return &FloatVal{F: xf * yf}, nil
+	case token.SLASH:
+		if yf == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return &FloatVal{F: xf / yf}, nil
+	case token.PERCENT:
+		if yf == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return &FloatVal{F: math.Mod(xf, yf)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported operator %s", op.Op())
+	}
+}
+
+// unaryOp applies the prefix operator op to x.
+func unaryOp(op token.Type, x Value) (Value, error) {
+	switch op {
+	case token.BANG:
+		return boolVal(!truthy(x)), nil
+	case token.MINUS:
+		n, err := toNumber(x)
+		if err != nil {
+			return nil, fmt.Errorf("invalid operation: operator - not defined on %s: %w", typeName(x), err)
+		}
+		if i, ok := n.(*IntVal); ok {
+			return &IntVal{I: -i.I}, nil
+		}
+		return &FloatVal{F: -toFloat(n)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported operator %s", op.Op())
+	}
+}
+
+// compare compares x and y numerically if both are numbers, and as strings otherwise. A string
+// or node that looks like a number is compared numerically against a number, so that
+// (number) @n { if n > 10 {...} } does what you'd expect. Like AWK, the result is 1 if the
+// comparison holds and 0 if not.
+func compare(op token.Type, x, y Value) Value {
+	var cmp int
+	if xf, yf, ok := numericPair(x, y); ok {
+		switch {
+		case xf < yf:
+			cmp = -1
+		case xf > yf:
+			cmp = 1
+		}
+	} else {
+		cmp = strings.Compare(toString(x), toString(y))
+	}
+
+	var result bool
+	switch op {
+	case token.EQUAL_EQUAL:
+		result = cmp == 0
+	case token.BANG_EQUAL:
+		result = cmp != 0
+	case token.LESS:
+		result = cmp < 0
+	case token.LESS_EQUAL:
+		result = cmp <= 0
+	case token.GREATER:
+		result = cmp > 0
+	case token.GREATER_EQUAL:
+		result = cmp >= 0
+	}
+	return boolVal(result)
+}
+
+// numericPair converts x and y to floats for a numeric comparison. At least one of them has
+// to be a number and the other has to be convertible to one.
+func numericPair(x, y Value) (xf, yf float64, ok bool) {
+	if !isNumber(x) && !isNumber(y) {
+		return 0, 0, false
+	}
+	xn, err := toNumber(x)
+	if err != nil {
+		return 0, 0, false
+	}
+	yn, err := toNumber(y)
+	if err != nil {
+		return 0, 0, false
+	}
+	return toFloat(xn), toFloat(yn), true
+}
+
+func isNumber(v Value) bool {
+	switch v.(type) {
+	case *IntVal, *FloatVal:
+		return true
+	}
+	return false
+}
+
+// toNumber converts v to an *IntVal or *FloatVal. Strings and nodes are parsed from their
+// text, anything else is an error.
+func toNumber(v Value) (Value, error) {
+	switch v := v.(type) {
+	case *IntVal, *FloatVal:
+		return v, nil
+	case *StringVal, *NodeVal:
+		s := strings.TrimSpace(toString(v))
+		if s == "" {
+			// Like AWK, an empty (or uninitialized) value is 0
+			return &IntVal{I: 0}, nil
+		}
+		if i, err := strconv.Atoi(s); err == nil {
+			return &IntVal{I: i}, nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return &FloatVal{F: f}, nil
+		}
+		return nil, fmt.Errorf("cannot convert %q to a number", s)
+	default:
+		return nil, fmt.Errorf("cannot convert %s to a number", typeName(v))
+	}
+}
+
+// toInt converts v to an integer, truncating floats.
+func toInt(v Value) (int, error) {
+	n, err := toNumber(v)
+	if err != nil {
+		return 0, err
+	}
+	if i, ok := n.(*IntVal); ok {
+		return i.I, nil
+	}
+	return int(toFloat(n)), nil
+}
+
+func toFloat(n Value) float64 {
+	switch n := n.(type) {
+	case *IntVal:
+		return float64(n.I)
+	case *FloatVal:
+		return n.F
+	}
+	return math.NaN()
+}
+
+func boolVal(b bool) *IntVal {
+	if b {
+		return &IntVal{I: 1}
+	}
+	return &IntVal{I: 0}
+}
+
+// truthy is false for 0, the empty string and empty dicts and lists, and true otherwise.
+func truthy(v Value) bool {
+	switch v := v.(type) {
+	case *IntVal:
+		return v.I != 0
+	case *FloatVal:
+		return v.F != 0
+	case *StringVal:
+		return v.S != ""
+	case *DictVal:
+		return len(v.D) > 0
+	case *ListVal:
+		return len(v.L) > 0
+	default:
+		return v != nil
+	}
+}
+
+// toString converts v to the string used for comparisons and concatenation. Nodes are
+// converted to their source text and dicts and lists to JSON.
+func toString(v Value) string {
+	switch v := v.(type) {
+	case *StringVal:
+		return v.S
+	case *IntVal:
+		return strconv.Itoa(v.I)
+	case *FloatVal:
+		return formatFloat(v.F)
+	case *NodeVal:
+		return v.Content()
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("<bad:%T>", v)
+		}
+		return string(b)
+	}
+}
+
+// formatFloat formats f like AWK does by default (OFMT of %.6g), except that integral values
+// are printed without an exponent.
+func formatFloat(f float64) string {
+	if f == math.Trunc(f) && math.Abs(f) < 1e16 {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(f, 'g', 6, 64)
+}
@@ -0,0 +1,217 @@
+package eval
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/masp/awktree/ast"
+	"github.com/masp/awktree/token"
+)
+
+// Like AWK, an output file or command is opened by the first print that's redirected to it
+// and stays open for the rest of the run, so print(x) > "file" only truncates the file once
+// and print(x) | "sort" sorts everything that was printed. The same goes for the commands
+// read by getline. close(name) closes any of them early, for example to read a file back or
+// to see the output of a command.
+
+type output interface {
+	io.Writer
+	Close() error
+}
+
+type outputFile struct {
+	f *os.File
+	w *bufio.Writer
+}
+
+func (o *outputFile) Write(b []byte) (int, error) { return o.w.Write(b) }
+
+func (o *outputFile) Close() error {
+	return errors.Join(o.w.Flush(), o.f.Close())
+}
+
+// outputPipe is a command reading what's printed to it.
+type outputPipe struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+}
+
+func (o *outputPipe) Write(b []byte) (int, error) { return o.stdin.Write(b) }
+
+func (o *outputPipe) Close() error {
+	return errors.Join(o.stdin.Close(), o.cmd.Wait())
+}
+
+// inputPipe is a command that getline reads from.
+type inputPipe struct {
+	cmd    *exec.Cmd
+	stdout io.ReadCloser
+	r      *bufio.Reader
+}
+
+func (i *inputPipe) Close() error {
+	i.stdout.Close()
+	return i.cmd.Wait()
+}
+
+// command creates a command that runs cmd with the shell, like AWK. Running commands has to be
+// allowed by Options.AllowCommands.
+func (c *evalCtx) command(what, cmd string) (*exec.Cmd, error) {
+	if !c.allowCommands {
+		return nil, fmt.Errorf("%s: running commands is not allowed (see Options.AllowCommands)", what)
+	}
+	command := exec.Command("sh", "-c", cmd)
+	command.Stdout = c.Output
+	command.Stderr = c.Stderr
+	return command, nil
+}
+
+// execRedirect runs the print or printf call of stmt with its output going to stmt.Dest.
+func (p *Program) execRedirect(c *evalCtx, stmt *ast.RedirectStmt) error {
+	dest, err := p.eval(c, stmt.Dest)
+	if err != nil {
+		return err
+	}
+	name := toString(dest)
+	out, ok := p.outputs[name]
+	if !ok {
+		if out, err = c.openOutput(stmt.Op, name); err != nil {
+			return fmt.Errorf("%s: %w", ast.Format(stmt), err)
+		}
+		p.outputs[name] = out
+	}
+
+	rc := *c
+	rc.Output = out
+	_, err = p.runFunc(&rc, stmt.Call)
+	return err
+}
+
+func (c *evalCtx) openOutput(op token.Type, name string) (output, error) {
+	switch op {
+	case token.PIPE:
+		cmd, err := c.command("print", name)
+		if err != nil {
+			return nil, err
+		}
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, err
+		}
+		return &outputPipe{cmd: cmd, stdin: stdin}, nil
+	default:
+		flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if op == token.GREATER_GREATER {
+			flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+		}
+		f, err := os.OpenFile(name, flags, 0644)
+		if err != nil {
+			return nil, err
+		}
+		return &outputFile{f: f, w: bufio.NewWriter(f)}, nil
+	}
+}
+
+// getline evaluates cmd | getline var. Like AWK, the result is 1 if a line was read into var
+// and 0 at the end of the output, which stays that way until the command is closed.
+func (p *Program) getline(c *evalCtx, x *ast.GetlineExpr) (Value, error) {
+	v, err := p.eval(c, x.Cmd)
+	if err != nil {
+		return nil, err
+	}
+	name := toString(v)
+	in, ok := p.inputs[name]
+	if !ok {
+		cmd, err := c.command("getline", name)
+		if err != nil {
+			return nil, err
+		}
+		cmd.Stdout = nil
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("%s: %w", ast.Format(x), err)
+		}
+		in = &inputPipe{cmd: cmd, stdout: stdout, r: bufio.NewReader(stdout)}
+		p.inputs[name] = in
+	}
+
+	line, err := in.r.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return &IntVal{I: 0}, nil
+	} else if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%s: %w", ast.Format(x), err)
+	}
+	line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+	if err := p.assign(c, x.Var, &StringVal{S: line}); err != nil {
+		return nil, err
+	}
+	return &IntVal{I: 1}, nil
+}
+
+// system runs cmd and returns its exit status.
+func (p *Program) system(c *evalCtx, cmd string) (Value, error) {
+	command, err := c.command("system", cmd)
+	if err != nil {
+		return nil, err
+	}
+	return exitStatus(command.Run())
+}
+
+// exitStatus converts the error of running a command to its exit status.
+func exitStatus(err error) (Value, error) {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &IntVal{I: exitErr.ExitCode()}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &IntVal{I: 0}, nil
+}
+
+// closeOutput closes the file or command named name. Like AWK, the result is 0 if it was
+// closed, the exit status for a command and -1 if it wasn't open.
+func (p *Program) closeOutput(name string) (Value, error) {
+	var err error
+	if out, ok := p.outputs[name]; ok {
+		delete(p.outputs, name)
+		err = out.Close()
+	} else if in, ok := p.inputs[name]; ok {
+		delete(p.inputs, name)
+		err = in.Close()
+	} else {
+		return &IntVal{I: -1}, nil
+	}
+	status, err := exitStatus(err)
+	if err != nil {
+		return nil, fmt.Errorf("close %s: %w", name, err)
+	}
+	return status, nil
+}
+
+// Close closes every file and command that's still open. It should be called once at the
+// end of the run, after End.
+func (p *Program) Close() error {
+	var errs []error
+	for name := range p.outputs {
+		if _, err := p.closeOutput(name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for name := range p.inputs {
+		if _, err := p.closeOutput(name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -2,6 +2,7 @@ package eval
 
 import (
 	"fmt"
+	"log"
 	"slices"
 	"strings"
 
@@ -9,11 +10,13 @@ import (
 	sitter "github.com/smacker/go-tree-sitter"
 )
 
-func (l *language) formatPattern(pa *ast.QueryPattern) (rootCapture string, tsPattern string, err error) {
-	l.replaceSymbols(pa)
+func (l *language) formatPattern(pa ast.Pattern) (rootCapture string, tsPattern string, err error) {
+	if err = l.replaceSymbols(pa); err != nil {
+		return
+	}
 	tsPattern = ast.Format(pa)
-	if pa.Capture != nil {
-		rootCapture = pa.Capture.Name
+	if capture := ast.Capture(pa); capture != nil {
+		rootCapture = capture.Name
 	} else {
 		tsPattern += " @__match" // implicit variable for the entire match unless there already is one
 		rootCapture = "@__match"
@@ -21,6 +24,39 @@ func (l *language) formatPattern(pa *ast.QueryPattern) (rootCapture string, tsPa
 	return
 }
 
+// query is a pattern compiled for a language, with the parts tree-sitter doesn't check for us.
+type query struct {
+	q           *sitter.Query
+	rootCapture string
+	preds       []*ast.QueryPredicate
+	repeated    []string // see repeatedCaptures
+}
+
+func (l *language) compileQuery(pattern ast.Pattern) (*query, error) {
+	if ex, ok := pattern.(*ast.ExamplePattern); ok {
+		var err error
+		if pattern, err = l.compileExample(ex); err != nil {
+			return nil, err
+		}
+	}
+	rootCapture, tsPattern, err := l.formatPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("query pattern: %s", tsPattern)
+	q, err := sitter.NewQuery([]byte(tsPattern), l.lang)
+	if err != nil {
+		return nil, err
+	}
+	return &query{
+		q:           q,
+		rootCapture: rootCapture,
+		preds:       patternPredicates(pattern),
+		repeated:    repeatedCaptures(pattern),
+	}, nil
+}
+
 // To support abbrevations of symbols, we define the sorted list of all symbols for a given language,
 // and then do a longest-prefix search to find the appropriate symbol and replace it in the resulting
 // query pattern.
@@ -52,36 +88,38 @@ func (l *language) replaceSymbols(x ast.Node) error {
 	return ast.Walk(x, ast.VisitorFunc(func(x ast.Node) error {
 		switch x := x.(type) {
 		case *ast.QueryPattern:
-			if isPunctuation(x.Symbol.Name) {
+			if x.Symbol == nil || isWildcard(x.Symbol.Name) {
 				return nil
 			}
 			opts := l.lookupAbbrev(x.Symbol.Name)
-			if len(opts) > 1 {
+			switch {
+			case len(opts) > 1:
 				return fmt.Errorf("ambiguous symbol abbreviation %s (%+v)", x.Symbol.Name, opts)
-			}
-			x.Symbol.Name = string(opts[0])
+			case len(opts) == 1:
+				x.Symbol.Name = string(opts[0])
+			} // otherwise leave it for tree-sitter to report the unknown symbol
 		}
 		return nil
 	}))
 }
 
-// isPunctuation is true if the symbol is plain ASCII letters (e.g. identifier) and not
-//
-//	_, !=, ==, or some symbol similar.
-func isPunctuation(sym string) bool {
-	return sym[0] == '_'
+// isWildcard is true for the symbol of (_), which matches any named node and isn't
+// abbreviated. Other symbols starting with _ are looked up like any other.
+func isWildcard(sym string) bool {
+	return sym == "_"
 }
 
 // lookupAbbrev will look up all the potential matches abbrev symbol has in the language.
 // If there are multiple matches, the abbrev is ambiguous.
 func (l *language) lookupAbbrev(abbrev string) []symbol {
+	if i, found := slices.BinarySearch(l.symbols, symbol(abbrev)); found {
+		return []symbol{l.symbols[i]}
+	}
+
 	// Search the symbols by the first part first. For all matches,
 	// we then check the prefix of the rest.
 	parts := strings.Split(abbrev, "_")
-	i, found := slices.BinarySearch(l.symbols, symbol(parts[0]))
-	if found {
-		return []symbol{l.symbols[i]}
-	}
+	i, _ := slices.BinarySearch(l.symbols, symbol(parts[0]))
 
 	// Otherwise, search the candidates for the longest prefix match. If abbrev is a prefix
 	// of a symbol, BinarySearch will pick the index of the first symbol that has the prefix.
@@ -111,7 +149,8 @@ func matchAbbrev(sym symbol, abbrev symbol) (matching int, eol bool) {
 		if strings.HasPrefix(symParts[i], abbrevParts[i]) {
 			matching += len(abbrevParts[i])
 		} else {
-			return 0, true
+			// The symbols are sorted, so once the first part stops matching none of the rest will
+			return 0, i == 0
 		}
 	}
 	return matching, false
@@ -21,6 +21,8 @@ func TestMatchAbbreviation(t *testing.T) {
 		{"ar_pat_rep", []symbol{"array_pattern_repeat1"}},
 		{"id", []symbol{"identifier"}},
 		{"ca", []symbol{"case", "catch"}},
+		{"expression_statement", []symbol{"expression_statement"}},
+		{"func_exp", []symbol{"function_expression"}},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,142 @@
+package eval
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/masp/awktree/ast"
+	"github.com/masp/awktree/token"
+)
+
+// Predicates like (#eq? @a @b) are checked here instead of with go-tree-sitter's
+// FilterPredicates, which doesn't know #any-of? and panics on a bad regular expression.
+
+// compilePredicates checks that every predicate is known and has valid arguments, so that
+// mistakes are reported before any input is read.
+func (p *Program) compilePredicates() error {
+	var errs token.ErrorList
+	ast.Walk(p.Ast, ast.VisitorFunc(func(n ast.Node) error {
+		if pred, ok := n.(*ast.QueryPredicate); ok {
+			if err := p.checkPredicate(pred); err != nil {
+				errs.Add(p.Ast.File.Position(pred.Pos()), err)
+			}
+		}
+		return nil
+	}))
+	return errs.Err()
+}
+
+func (p *Program) checkPredicate(pred *ast.QueryPredicate) error {
+	name := pred.Name.Name
+	if len(pred.Args) == 0 || !isCapture(pred.Args[0]) {
+		return fmt.Errorf("%s: first argument must be a capture", name)
+	}
+	switch predicateOp(name) {
+	case "eq?":
+		if len(pred.Args) != 2 {
+			return fmt.Errorf("%s: wanted 2 arguments, got %d", name, len(pred.Args))
+		}
+	case "match?":
+		if len(pred.Args) != 2 || isCapture(pred.Args[1]) {
+			return fmt.Errorf("%s: wanted a capture and a regular expression", name)
+		}
+		if _, err := p.regexp(&StringVal{S: unquoteQuery(pred.Args[1].(*ast.String).Value)}); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	case "any-of?":
+		for _, arg := range pred.Args[1:] {
+			if isCapture(arg) {
+				return fmt.Errorf("%s: wanted a capture and a list of strings", name)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown predicate %s", name)
+	}
+	return nil
+}
+
+// predicateOp is the name of the predicate without # and not-, e.g. eq? for #not-eq?.
+func predicateOp(name string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(name, "#"), "not-")
+}
+
+func isCapture(arg ast.Expr) bool {
+	_, ok := arg.(*ast.Ident)
+	return ok
+}
+
+// patternPredicates returns all the predicates in pattern, including those in subpatterns.
+// Like tree-sitter, each one applies to the whole match.
+func patternPredicates(pattern ast.Pattern) (preds []*ast.QueryPredicate) {
+	ast.Walk(pattern, ast.VisitorFunc(func(n ast.Node) error {
+		if pred, ok := n.(*ast.QueryPredicate); ok {
+			preds = append(preds, pred)
+		}
+		return nil
+	}))
+	return
+}
+
+// filterPredicates is true if the current match satisfies every predicate. A predicate on a
+// capture that isn't part of the match is ignored, and one on a quantified capture must hold
+// for every node in it.
+func (p *Program) filterPredicates(c *evalCtx, preds []*ast.QueryPredicate) (bool, error) {
+	for _, pred := range preds {
+		for _, node := range captureNodes(c.Vars[pred.Args[0].(*ast.Ident).Name]) {
+			matched, err := p.checkNode(c, pred, toString(node))
+			if err != nil {
+				return false, err
+			}
+			if negated := strings.HasPrefix(pred.Name.Name, "#not-"); matched == negated {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// captureNodes are the nodes bound to a capture: none if it didn't match, or all of them if
+// it's quantified.
+func captureNodes(v Value) []Value {
+	switch v := v.(type) {
+	case *NodeVal:
+		return []Value{v}
+	case *ListVal:
+		return v.L
+	}
+	return nil
+}
+
+// checkNode is true if text, the text of a node in the first capture of pred, matches pred
+// without its not-.
+func (p *Program) checkNode(c *evalCtx, pred *ast.QueryPredicate, text string) (bool, error) {
+	switch predicateOp(pred.Name.Name) {
+	case "eq?":
+		return text == p.predicateArg(c, pred.Args[1]), nil
+	case "match?":
+		re, err := p.regexp(&StringVal{S: unquoteQuery(pred.Args[1].(*ast.String).Value)})
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(text), nil
+	case "any-of?":
+		return slices.ContainsFunc(pred.Args[1:], func(arg ast.Expr) bool {
+			return text == p.predicateArg(c, arg)
+		}), nil
+	}
+	return false, fmt.Errorf("unknown predicate %s", pred.Name.Name)
+}
+
+func (p *Program) predicateArg(c *evalCtx, arg ast.Expr) string {
+	switch arg := arg.(type) {
+	case *ast.Ident:
+		if v, ok := c.Vars[arg.Name]; ok {
+			return toString(v)
+		}
+		return ""
+	case *ast.String:
+		return unquoteQuery(arg.Value)
+	}
+	panic(fmt.Sprintf("unexpected predicate argument %T", arg))
+}
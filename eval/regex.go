@@ -0,0 +1,125 @@
+package eval
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/masp/awktree/ast"
+	"github.com/masp/awktree/token"
+)
+
+// Regular expressions use RE2 syntax (see the regexp package). A regex literal like /fo+/
+// evaluates to its source, so like AWK any string can be used as a dynamic regex: s ~ "fo+".
+
+// compileRegexps checks the regex literals of the program so that mistakes are reported
+// before running anything.
+func (p *Program) compileRegexps() error {
+	var errs token.ErrorList
+	ast.Walk(p.Ast, ast.VisitorFunc(func(n ast.Node) error {
+		if re, ok := n.(*ast.Regex); ok {
+			if _, err := p.regexp(&StringVal{S: re.Value}); err != nil {
+				errs.Add(p.Ast.File.Position(re.Pos()), err)
+			}
+		}
+		return nil
+	}))
+	return errs.Err()
+}
+
+// regexp compiles v as a regular expression, caching the result for the next match.
+func (p *Program) regexp(v Value) (*regexp.Regexp, error) {
+	s := toString(v)
+	if re, ok := p.regexps[s]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(s)
+	if err != nil {
+		return nil, err
+	}
+	p.regexps[s] = re
+	return re, nil
+}
+
+// matchOp evaluates x ~ re and x !~ re.
+func (p *Program) matchOp(op token.Type, x, re Value) (Value, error) {
+	r, err := p.regexp(re)
+	if err != nil {
+		return nil, err
+	}
+	matched := r.MatchString(toString(x))
+	return boolVal(matched == (op == token.TILDE)), nil
+}
+
+// match(s, re) returns the leftmost match of re in s followed by its capture groups, or an
+// empty list if there is none. A group that didn't take part in the match is "".
+func (p *Program) match(args []Value) (Value, error) {
+	re, err := p.regexp(args[1])
+	if err != nil {
+		return nil, err
+	}
+	groups := re.FindStringSubmatch(toString(args[0]))
+	list := &ListVal{L: make([]Value, len(groups))}
+	for i, group := range groups {
+		list.L[i] = &StringVal{S: group}
+	}
+	return list, nil
+}
+
+// sub(re, repl, s) returns s with the first match of re replaced by repl, and gsub all of
+// them. repl can refer to the groups of the match with $1 or ${name}. Without s, they
+// rewrite the text of @.
+func (p *Program) sub(c *evalCtx, name string, args []Value) (Value, error) {
+	re, err := p.regexp(args[0])
+	if err != nil {
+		return nil, err
+	}
+	repl := toString(args[1])
+	var s string
+	if len(args) > 2 {
+		s = toString(args[2])
+	} else if root, ok := c.Vars["@"]; ok {
+		s = toString(root)
+	}
+
+	if name == "gsub" {
+		return &StringVal{S: re.ReplaceAllString(s, repl)}, nil
+	}
+	loc := re.FindStringSubmatchIndex(s)
+	if loc == nil {
+		return &StringVal{S: s}, nil
+	}
+	result := re.ExpandString([]byte(s[:loc[0]]), repl, s, loc)
+	return &StringVal{S: string(result) + s[loc[1]:]}, nil
+}
+
+// execSub runs sub or gsub as a statement, which rewrites its target in place like AWK: a
+// node (or @ if there is no target) is replaced by an edit of the input and a variable is
+// assigned the rewritten string.
+func (p *Program) execSub(c *evalCtx, f *ast.Call) error {
+	args, err := p.evalArgs(c, f)
+	if err != nil {
+		return err
+	}
+	result, err := p.sub(c, f.FuncName.Name, args)
+	if err != nil {
+		return err
+	}
+
+	target := c.Vars["@"]
+	if len(args) > 2 {
+		target = args[2]
+	}
+	if n, ok := target.(*NodeVal); ok {
+		c.edit(n, toString(result))
+		return nil
+	}
+	if len(f.Args) < 3 {
+		return fmt.Errorf("%s: there is no @ to rewrite", f.FuncName.Name)
+	}
+	switch f.Args[2].(type) {
+	case *ast.Ident, *ast.IndexExpr, *ast.SelectorExpr:
+		return p.assign(c, f.Args[2], result)
+	default:
+		return fmt.Errorf("%s: cannot rewrite %s", f.FuncName.Name, ast.Format(f.Args[2]))
+	}
+}
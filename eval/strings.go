@@ -0,0 +1,148 @@
+package eval
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// The string builtins convert their arguments to strings first (a node to its text), and
+// count characters rather than bytes. Like AWK, positions in substr and index start at 1.
+
+// stringFunc calls the string builtin name on args, which the caller has already checked the
+// number of.
+func stringFunc(name string, args []Value) (Value, error) {
+	switch name {
+	case "length":
+		return length(args[0]), nil
+	case "substr":
+		return substr(args)
+	case "index":
+		s, t := toString(args[0]), toString(args[1])
+		i := strings.Index(s, t)
+		if i < 0 {
+			return &IntVal{I: 0}, nil
+		}
+		return &IntVal{I: utf8.RuneCountInString(s[:i]) + 1}, nil
+	case "split":
+		// split(s) splits on runs of whitespace like AWK, split(s, sep) on every sep
+		s := toString(args[0])
+		var parts []string
+		if len(args) == 1 {
+			parts = strings.Fields(s)
+		} else if s != "" {
+			parts = strings.Split(s, toString(args[1]))
+		}
+		list := &ListVal{L: make([]Value, len(parts))}
+		for i, part := range parts {
+			list.L[i] = &StringVal{S: part}
+		}
+		return list, nil
+	case "sprintf":
+		s, err := sprintf(toString(args[0]), args[1:])
+		if err != nil {
+			return nil, fmt.Errorf("sprintf: %w", err)
+		}
+		return &StringVal{S: s}, nil
+	case "toupper":
+		return &StringVal{S: strings.ToUpper(toString(args[0]))}, nil
+	case "tolower":
+		return &StringVal{S: strings.ToLower(toString(args[0]))}, nil
+	case "trim":
+		// trim(s) removes the surrounding whitespace, trim(s, chars) any of the chars
+		if len(args) == 1 {
+			return &StringVal{S: strings.TrimSpace(toString(args[0]))}, nil
+		}
+		return &StringVal{S: strings.Trim(toString(args[0]), toString(args[1]))}, nil
+	default:
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+}
+
+// substr(s, m) is s from the m-th character on and substr(s, m, n) the (at most) n characters
+// from there. Like AWK, the range is clamped to s, so substr("abc", 0, 2) is "a".
+func substr(args []Value) (Value, error) {
+	runes := []rune(toString(args[0]))
+	start, err := toInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+	end := len(runes) + 1
+	if len(args) > 2 {
+		n, err := toInt(args[2])
+		if err != nil {
+			return nil, err
+		}
+		end = min(end, start+n)
+	}
+	start = max(start, 1)
+	if end <= start {
+		return &StringVal{}, nil
+	}
+	return &StringVal{S: string(runes[start-1 : end-1])}, nil
+}
+
+// sprintf formats args like Go's fmt.Sprintf, converting each argument to what its verb
+// expects: %d and %c want an integer, %f, %e and %g a float, %s, %q and %v any value as a
+// string (%v keeps numbers as numbers).
+func sprintf(format string, args []Value) (string, error) {
+	var buf strings.Builder
+	argi := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			buf.WriteByte(format[i])
+			continue
+		}
+
+		// Flags, width and precision are passed through as they are
+		start := i
+		i++
+		for i < len(format) && strings.IndexByte("+-# 0123456789.", format[i]) >= 0 {
+			i++
+		}
+		if i == len(format) {
+			return "", fmt.Errorf("format %q ends in the middle of a verb", format)
+		}
+		verb := format[i]
+		if verb == '%' {
+			buf.WriteByte('%')
+			continue
+		}
+		if argi == len(args) {
+			return "", fmt.Errorf("not enough arguments for format %q", format)
+		}
+		arg, err := formatArg(verb, args[argi])
+		if err != nil {
+			return "", err
+		}
+		argi++
+		fmt.Fprintf(&buf, format[start:i+1], arg)
+	}
+	return buf.String(), nil
+}
+
+// formatArg converts v to the Go value that the verb formats.
+func formatArg(verb byte, v Value) (any, error) {
+	switch verb {
+	case 'd', 'c', 'x', 'X', 'o', 'b':
+		return toInt(v)
+	case 'f', 'F', 'e', 'E', 'g', 'G':
+		n, err := toNumber(v)
+		if err != nil {
+			return nil, err
+		}
+		return toFloat(n), nil
+	case 's', 'q':
+		return toString(v), nil
+	case 'v':
+		switch v := v.(type) {
+		case *IntVal:
+			return v.I, nil
+		case *FloatVal:
+			return formatFloat(v.F), nil
+		}
+		return toString(v), nil
+	default:
+		return nil, fmt.Errorf("unknown verb %%%c", verb)
+	}
+}
@@ -0,0 +1,16 @@
+package main
+
+import "fmt"
+
+func run() error {
+	err := work()
+	if err != nil {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("run: %w", err)
+	}
+	fmt.Println("done")
+	fmt.Println("a", "b")
+	return nil
+}
@@ -0,0 +1,77 @@
+package eval
+
+import (
+	"github.com/masp/awktree/ast"
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// A capture name that's used more than once in a pattern, like @a in
+// (binary_expression left: (_) @a right: (_) @a), only matches if all of its nodes are the
+// same code. Tree-sitter doesn't check this, so it's done when filtering the matches.
+
+// repeatedCaptures are the capture names used more than once in pattern.
+func repeatedCaptures(pattern ast.Pattern) (names []string) {
+	count := make(map[string]int)
+	ast.Walk(pattern, ast.VisitorFunc(func(n ast.Node) error {
+		if p, ok := n.(ast.Pattern); ok {
+			if capture := ast.Capture(p); capture != nil {
+				count[capture.Name]++
+				if count[capture.Name] == 2 {
+					names = append(names, capture.Name)
+				}
+			}
+		}
+		return nil
+	}))
+	return
+}
+
+// unify is true if every repeated capture matched the same code in all of its places, and
+// binds the capture to the first of them.
+func (c *evalCtx) unify(names []string) bool {
+	for _, name := range names {
+		nodes := captureNodes(c.Vars[name])
+		if len(nodes) == 0 {
+			continue
+		}
+		first := nodes[0].(*NodeVal)
+		for _, other := range nodes[1:] {
+			if !sameCode(first.N, other.(*NodeVal).N, c.Src) {
+				return false
+			}
+		}
+		c.Vars[name] = first
+	}
+	return true
+}
+
+// sameCode is true if a and b have the same structure and tokens, ignoring whitespace and
+// comments.
+func sameCode(a, b *sitter.Node, src []byte) bool {
+	if a.Type() != b.Type() {
+		return false
+	}
+	ac, bc := codeChildren(a), codeChildren(b)
+	if len(ac) != len(bc) {
+		return false
+	}
+	if len(ac) == 0 {
+		return a.Content(src) == b.Content(src)
+	}
+	for i := range ac {
+		if !sameCode(ac[i], bc[i], src) {
+			return false
+		}
+	}
+	return true
+}
+
+// codeChildren are the children of n without extras like comments.
+func codeChildren(n *sitter.Node) (children []*sitter.Node) {
+	for i := 0; i < int(n.ChildCount()); i++ {
+		if child := n.Child(i); !child.IsExtra() {
+			children = append(children, child)
+		}
+	}
+	return
+}
@@ -1,9 +1,11 @@
 package eval
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
-	"strconv"
+	"math"
+	"slices"
 
 	sitter "github.com/smacker/go-tree-sitter"
 )
@@ -15,9 +17,31 @@ type Value interface {
 }
 
 func (IntVal) isValue()    {}
+func (FloatVal) isValue()  {}
 func (StringVal) isValue() {}
 func (NodeVal) isValue()   {}
 func (DictVal) isValue()   {}
+func (ListVal) isValue()   {}
+
+// typeName is the name of the value's type as shown to users in error messages.
+func typeName(v Value) string {
+	switch v.(type) {
+	case *IntVal:
+		return "int"
+	case *FloatVal:
+		return "float"
+	case *StringVal:
+		return "string"
+	case *NodeVal:
+		return "node"
+	case *DictVal:
+		return "dict"
+	case *ListVal:
+		return "list"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
 
 type NodeVal struct {
 	Src []byte
@@ -27,6 +51,60 @@ type NodeVal struct {
 func (n *NodeVal) Content() string { return n.N.Content(n.Src) }
 func (n *NodeVal) Bytes() []byte   { return n.Src[n.N.StartByte():n.N.EndByte()] }
 
+// field returns the child of n for the field name, or the empty value if there is none.
+func (n *NodeVal) field(name string) Value {
+	child := n.N.ChildByFieldName(name)
+	if child == nil {
+		return &StringVal{}
+	}
+	return &NodeVal{N: child, Src: n.Src}
+}
+
+// property returns the value of a node property like n.type or n.start_line. Lines and
+// columns start at 1 like token.Position, and columns count bytes.
+func (n *NodeVal) property(name string) (Value, bool) {
+	switch name {
+	case "type":
+		return &StringVal{S: n.N.Type()}, true
+	case "text":
+		return &StringVal{S: n.Content()}, true
+	case "start_line":
+		return &IntVal{I: int(n.N.StartPoint().Row) + 1}, true
+	case "start_col":
+		return &IntVal{I: int(n.N.StartPoint().Column) + 1}, true
+	case "end_line":
+		return &IntVal{I: int(n.N.EndPoint().Row) + 1}, true
+	case "end_col":
+		return &IntVal{I: int(n.N.EndPoint().Column) + 1}, true
+	case "start_byte":
+		return &IntVal{I: int(n.N.StartByte())}, true
+	case "end_byte":
+		return &IntVal{I: int(n.N.EndByte())}, true
+	case "field":
+		return &StringVal{S: n.fieldName()}, true
+	case "named":
+		return boolVal(n.N.IsNamed()), true
+	case "has_error":
+		return boolVal(n.N.HasError()), true
+	default:
+		return nil, false
+	}
+}
+
+// fieldName is the name of the field n is in its parent, or "" if it isn't in one.
+func (n *NodeVal) fieldName() string {
+	parent := n.N.Parent()
+	if parent == nil {
+		return ""
+	}
+	for i := 0; i < int(parent.ChildCount()); i++ {
+		if parent.Child(i).Equal(n.N) {
+			return parent.FieldNameForChild(i)
+		}
+	}
+	return ""
+}
+
 func (n *NodeVal) MarshalJSON() ([]byte, error) {
 	return json.Marshal(n.N.Content(n.Src))
 }
@@ -59,28 +137,177 @@ func (i *IntVal) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, &i.I)
 }
 
+type FloatVal struct {
+	F float64
+}
+
+func (f *FloatVal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.F)
+}
+
+func (f *FloatVal) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &f.F)
+}
+
+// ListVal is an ordered list of values, like [a, b, c].
+type ListVal struct {
+	L []Value
+}
+
+func (l *ListVal) MarshalJSON() ([]byte, error) {
+	if l.L == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(l.L)
+}
+
+func (l *ListVal) UnmarshalJSON(data []byte) error {
+	var elts []any
+	if err := json.Unmarshal(data, &elts); err != nil {
+		return err
+	}
+	l.L = make([]Value, len(elts))
+	for i, elt := range elts {
+		l.L[i] = fromJSON(elt)
+	}
+	return nil
+}
+
+// DictVal is an AWK-style associative array. Keys compare by value rather than identity:
+// strings and numbers by their string form and nodes by their text, so "1" and 1 are the
+// same key and a node matches the string of its source. Entries keep their insertion order.
 type DictVal struct {
-	D map[Value]Value
+	D    map[string]Value // entries by key string
+	Keys []Value          // original key of every entry in insertion order
+}
+
+func NewDict() *DictVal {
+	return &DictVal{D: make(map[string]Value)}
+}
+
+// dictKey converts v to the string it's stored under in a dict.
+func dictKey(v Value) (string, error) {
+	switch v.(type) {
+	case *StringVal, *IntVal, *FloatVal, *NodeVal:
+		return toString(v), nil
+	default:
+		return "", fmt.Errorf("invalid dict key type %s", typeName(v))
+	}
+}
+
+func (d *DictVal) Get(key Value) (Value, bool, error) {
+	k, err := dictKey(key)
+	if err != nil {
+		return nil, false, err
+	}
+	v, ok := d.D[k]
+	return v, ok, nil
+}
+
+func (d *DictVal) Set(key Value, val Value) error {
+	k, err := dictKey(key)
+	if err != nil {
+		return err
+	}
+	if _, ok := d.D[k]; !ok {
+		d.Keys = append(d.Keys, key)
+	}
+	d.D[k] = val
+	return nil
+}
+
+func (d *DictVal) Delete(key Value) error {
+	k, err := dictKey(key)
+	if err != nil {
+		return err
+	}
+	if _, ok := d.D[k]; !ok {
+		return nil
+	}
+	delete(d.D, k)
+	d.Keys = slices.DeleteFunc(d.Keys, func(v Value) bool { return toString(v) == k })
+	return nil
+}
+
+// Clear removes all entries from d.
+func (d *DictVal) Clear() {
+	d.D = make(map[string]Value)
+	d.Keys = nil
 }
 
 func (d *DictVal) MarshalJSON() ([]byte, error) {
-	var m = make(map[string]Value)
-	for k, v := range d.D {
-		switch k := k.(type) {
-		case *StringVal:
-			m[k.S] = v
-		case *IntVal:
-			m[strconv.Itoa(k.I)] = v
-		case *NodeVal:
-			m[k.Content()] = v
-		default:
-			return nil, fmt.Errorf("unsupported key type %T", k)
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range d.Keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k := toString(key)
+		marshalledKey, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(marshalledKey)
+		buf.WriteByte(':')
+		marshalledVal, err := json.Marshal(d.D[k])
+		if err != nil {
+			return nil, err
 		}
-		m[k.(*StringVal).S] = v
+		buf.Write(marshalledVal)
 	}
-	return json.Marshal(m)
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
 }
 
 func (d *DictVal) UnmarshalJSON(data []byte) error {
-	return json.Unmarshal(data, &d.D)
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	d.Clear()
+	for _, k := range keys {
+		if err := d.Set(&StringVal{S: k}, fromJSON(m[k])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fromJSON converts a value decoded by encoding/json into a Value.
+func fromJSON(v any) Value {
+	switch v := v.(type) {
+	case string:
+		return &StringVal{S: v}
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1e16 {
+			return &IntVal{I: int(v)}
+		}
+		return &FloatVal{F: v}
+	case bool:
+		return boolVal(v)
+	case map[string]any:
+		d := NewDict()
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			d.Set(&StringVal{S: k}, fromJSON(v[k]))
+		}
+		return d
+	case []any:
+		l := &ListVal{L: make([]Value, len(v))}
+		for i, elt := range v {
+			l.L[i] = fromJSON(elt)
+		}
+		return l
+	default:
+		return &StringVal{}
+	}
 }
@@ -3,6 +3,7 @@ package lexer
 //go:generate re2go awktree.re -o awktree.go -i
 
 import (
+	"bytes"
 	"errors"
 
 	"github.com/masp/awktree/token"
@@ -13,6 +14,7 @@ var (
 	ErrInvalidString       = errors.New("invalid string")
 	ErrUnterminatedString  = errors.New("unterminated string")
 	ErrUnterminatedComment = errors.New("unterminated multiline comment")
+	ErrUnterminatedRegex   = errors.New("unterminated regular expression")
 )
 
 type TokenType int
@@ -39,6 +41,12 @@ type Lexer struct {
 	token     int // marks the start of the currently scanned token
 	prevToken Token
 
+	// Where we are in the program, to lex captures in expressions, see captureLiteral
+	braceDepth  int
+	parenDepth  int
+	inGuard     bool
+	guardParens int // parenDepth at the where of the guard
+
 	errors token.ErrorList
 }
 
@@ -64,6 +72,59 @@ func (l *Lexer) insertSemi() bool {
 	return false
 }
 
+// operandEnd are the tokens that can end an operand. A / after one of them is a division,
+// anywhere else it starts a regular expression like AWK.
+var operandEnd = map[token.Type]bool{
+	token.IDENT:           true,
+	token.INT:             true,
+	token.FLOAT:           true,
+	token.STRING:          true,
+	token.PATTERN:         true,
+	token.REGEX:           true,
+	token.RPAREN:          true,
+	token.RSQUARE_BRACKET: true,
+	token.RCURLY_BRACKET:  true,
+	token.PLUS_PLUS:       true,
+	token.MINUS_MINUS:     true,
+}
+
+func (l *Lexer) regexAllowed() bool {
+	return !operandEnd[l.prevToken.Type]
+}
+
+// lexRegex lexes a regular expression after its opening /, up to the closing / on the same
+// line. An escaped \/ is unescaped in the literal, anything else is kept for the regexp
+// package to interpret.
+func (l *Lexer) lexRegex() (pos token.Pos, tok token.Type, lit string, err error) {
+	pos = l.file.Pos(l.token)
+	var buf bytes.Buffer
+	buf.WriteByte('/')
+	for {
+		c := l.input[l.cursor]
+		switch {
+		case c == '\x00' || c == '\n':
+			err = ErrUnterminatedRegex
+			tok = token.EOF
+			return
+		case c == '\\' && l.input[l.cursor+1] == '/':
+			buf.WriteByte('/')
+			l.cursor += 2
+		case c == '\\' && l.input[l.cursor+1] != '\x00':
+			buf.Write(l.input[l.cursor : l.cursor+2])
+			l.cursor += 2
+		case c == '/':
+			buf.WriteByte('/')
+			l.cursor++
+			tok = token.REGEX
+			lit = buf.String()
+			return
+		default:
+			buf.WriteByte(c)
+			l.cursor++
+		}
+	}
+}
+
 func Lex(input []byte) ([]Token, error) {
 	lex := NewLexer("<string>", input)
 	tokens := lex.All()
@@ -114,5 +175,57 @@ func (l *Lexer) NextToken() (tok Token) {
 	if tok.Type != token.COMMENT {
 		l.prevToken = tok
 	}
+	l.track(tok.Type)
 	return
 }
+
+// track follows the braces, parentheses and where guards, which are the places where a
+// capture is part of an expression.
+func (l *Lexer) track(typ token.Type) {
+	switch typ {
+	case token.LCURLY_BRACKET:
+		l.braceDepth++
+		l.inGuard = false
+	case token.RCURLY_BRACKET:
+		if l.braceDepth > 0 {
+			l.braceDepth--
+		}
+	case token.LPAREN:
+		l.parenDepth++
+	case token.RPAREN:
+		l.parenDepth--
+		if l.parenDepth < l.guardParens {
+			l.inGuard = false // where guard inside the pattern of a constraint
+		}
+	case token.WHERE:
+		if l.braceDepth == 0 {
+			l.inGuard = true
+			l.guardParens = l.parenDepth
+		}
+	case token.NOT, token.INSIDE, token.HAS:
+		if l.braceDepth == 0 {
+			l.inGuard = false
+		}
+	}
+}
+
+// endLine ends a where guard at the end of its line, where the next rule starts.
+func (l *Lexer) endLine() {
+	if l.braceDepth == 0 && l.parenDepth <= l.guardParens {
+		l.inGuard = false
+	}
+}
+
+// captureLiteral is the capture just lexed. Like tree-sitter, a capture in a pattern can have
+// dashes, like @prev-id, but in an action or where guard @n-1 is @n minus 1, so there the name
+// stops at the first dash.
+func (l *Lexer) captureLiteral() string {
+	lit := l.input[l.token:l.cursor]
+	if l.braceDepth > 0 || l.inGuard {
+		if i := bytes.IndexByte(lit, '-'); i >= 0 {
+			l.cursor = l.token + i
+			lit = lit[:i]
+		}
+	}
+	return string(lit)
+}
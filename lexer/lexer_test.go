@@ -43,6 +43,99 @@ LPAREN(()
 RPAREN())
 		`,
 	},
+	{
+		`((comment)+ . (a)? @a (#not-eq? @a "x"))`,
+		`
+LPAREN(()
+	LPAREN(()
+		IDENT(comment)
+	RPAREN())
+	PLUS(+)
+	PERIOD(.)
+	LPAREN(()
+		IDENT(a)
+	RPAREN())
+	QUESTION(?)
+	IDENT(@a)
+	LPAREN(()
+		IDENT(#not-eq?)
+		IDENT(@a)
+		STRING("x")
+	RPAREN())
+RPAREN())
+`,
+	},
+	{
+		`x-=1 i++ @prev-id--`,
+		`
+IDENT(x)
+MINUS_EQUAL(-=)
+INT(1)
+IDENT(i)
+PLUS_PLUS(++)
+IDENT(@prev-id)
+MINUS_MINUS(--)
+`,
+	},
+	{
+		"(a) @prev-id where @n-1 {@n-1} (b) @next-id",
+		`
+LPAREN(()
+	IDENT(a)
+RPAREN())
+IDENT(@prev-id)
+WHERE(where)
+IDENT(@n)
+MINUS(-)
+INT(1)
+LCURLY_BRACKET({)
+	IDENT(@n)
+	MINUS(-)
+	INT(1)
+RCURLY_BRACKET(})
+LPAREN(()
+	IDENT(b)
+RPAREN())
+IDENT(@next-id)
+`,
+	},
+	{
+		`a%b&&!c||d!=1.5`,
+		`
+IDENT(a)
+PERCENT(%)
+IDENT(b)
+AND_AND(&&)
+BANG(!)
+IDENT(c)
+OR_OR(||)
+IDENT(d)
+BANG_EQUAL(!=)
+FLOAT(1.5)
+`,
+	},
+	{
+		`a/b ~ /x\/y/ !~ /[a-z]+\./ (c)/2`,
+		`
+IDENT(a)
+SLASH(/)
+IDENT(b)
+TILDE(~)
+REGEX(/x/y/)
+BANG_TILDE(!~)
+REGEX(/[a-z]+\./)
+LPAREN(()
+IDENT(c)
+RPAREN())
+SLASH(/)
+INT(2)
+`,
+	},
+}
+
+func TestLexUnterminatedRegex(t *testing.T) {
+	_, err := Lex([]byte("x ~ /abc\n/"))
+	assert.ErrorContains(t, err, "1:5: unterminated regular expression")
 }
 
 func TestLexT(t *testing.T) {
@@ -1,26 +1,33 @@
 package parser
 
 import (
+	"strings"
+
 	"github.com/masp/awktree/ast"
 	"github.com/masp/awktree/token"
 )
 
 var (
 	exprStart = map[token.Type]bool{
-		token.IDENT:          true,
-		token.INT:            true,
-		token.STRING:         true,
-		token.BANG:           true,
-		token.LCURLY_BRACKET: true,
-		token.FLOAT:          true,
+		token.IDENT:           true,
+		token.INT:             true,
+		token.STRING:          true,
+		token.BANG:            true,
+		token.LCURLY_BRACKET:  true,
+		token.FLOAT:           true,
+		token.MINUS:           true,
+		token.LPAREN:          true,
+		token.LSQUARE_BRACKET: true,
+		token.REGEX:           true,
 	}
 
 	exprEnd = map[token.Type]bool{
-		token.EOF:            true,
-		token.SEMICOLON:      true,
-		token.RPAREN:         true,
-		token.RCURLY_BRACKET: true,
-		token.COMMA:          true,
+		token.EOF:             true,
+		token.SEMICOLON:       true,
+		token.RPAREN:          true,
+		token.RCURLY_BRACKET:  true,
+		token.COMMA:           true,
+		token.RSQUARE_BRACKET: true,
 	}
 )
 
@@ -28,6 +35,22 @@ func (p *Parser) parseAction() *ast.Action {
 	action := &ast.Action{
 		OpenCurly: p.expect(token.LCURLY_BRACKET).Pos,
 	}
+	action.Stmts = p.parseStmtList()
+	action.CloseCurly = p.expect(token.RCURLY_BRACKET).Pos
+	return action
+}
+
+func (p *Parser) parseBlock() *ast.Block {
+	block := &ast.Block{
+		OpenCurly: p.expect(token.LCURLY_BRACKET).Pos,
+	}
+	block.Stmts = p.parseStmtList()
+	block.CloseCurly = p.expect(token.RCURLY_BRACKET).Pos
+	return block
+}
+
+// parseStmtList parses statements up to the closing } of the enclosing block.
+func (p *Parser) parseStmtList() (stmts []ast.Stmt) {
 	for {
 		t := p.peek()
 		if t.Type == token.RCURLY_BRACKET || t.Type == token.EOF {
@@ -37,25 +60,216 @@ func (p *Parser) parseAction() *ast.Action {
 		if stmt == nil {
 			break
 		}
-		action.Stmts = append(action.Stmts, stmt)
+		stmts = append(stmts, stmt)
+		for p.peek().Type == token.SEMICOLON {
+			p.eat()
+		}
 	}
-	action.CloseCurly = p.expect(token.RCURLY_BRACKET).Pos
-	return action
+	return stmts
 }
 
 func (p *Parser) parseStmt() ast.Stmt {
 	t := p.peek()
 	switch t.Type {
 	case token.IDENT:
-		return p.parseCall()
+		return p.parseSimpleStmt()
+	case token.STRING:
+		// Only "cmd" | getline var can start with a string
+		x := p.parseExpr()
+		if getline, ok := x.(*ast.GetlineExpr); ok {
+			return getline
+		} else if x != nil {
+			p.errorf(t.Pos, "unexpected token %s, wanted statement", t.String())
+		}
+		return nil
+	case token.IF:
+		return p.parseIf()
+	case token.WHILE:
+		return p.parseWhile()
+	case token.FOR:
+		return p.parseFor()
+	case token.DELETE:
+		stmt := &ast.DeleteStmt{Delete: p.eat().Pos, X: p.parseExpr()}
+		if stmt.X == nil {
+			return nil
+		}
+		p.checkAssignable(stmt.X)
+		return stmt
+	case token.RETURN:
+		return p.parseReturn()
+	case token.BREAK, token.CONTINUE:
+		p.eat()
+		if p.loopDepth == 0 {
+			p.errorf(t.Pos, "%s is not in a loop", t.Lit)
+		}
+		return &ast.BranchStmt{TokPos: t.Pos, Tok: t.Type}
 	default:
 		p.errorf(t.Pos, "unexpected token %s, wanted statement", t.String())
 	}
 	return nil
 }
 
+// parseFuncDecl parses func name(a, b) { ... }.
+func (p *Parser) parseFuncDecl() *ast.FuncDecl {
+	decl := &ast.FuncDecl{Func: p.expect(token.FUNC).Pos, Name: p.parseIdent()}
+	decl.Lparen = p.expect(token.LPAREN).Pos
+	for p.peek().Type == token.IDENT {
+		decl.Params = append(decl.Params, p.parseIdent())
+		if p.peek().Type != token.COMMA {
+			break
+		}
+		p.eat()
+	}
+	decl.Rparen = p.expect(token.RPAREN).Pos
+
+	p.inFunc = true
+	defer func() { p.inFunc = false }()
+	decl.Body = p.parseBlock()
+	return decl
+}
+
+// parseReturn parses return with an optional result, which has to start on the same line.
+func (p *Parser) parseReturn() *ast.ReturnStmt {
+	stmt := &ast.ReturnStmt{Return: p.expect(token.RETURN).Pos}
+	if !p.inFunc {
+		p.errorf(stmt.Return, "return is not in a function")
+	}
+	if next := p.peek(); exprStart[next.Type] && p.sameLine(next) {
+		stmt.Result = p.parseExpr()
+	}
+	return stmt
+}
+
+func (p *Parser) parseIf() *ast.IfStmt {
+	stmt := &ast.IfStmt{If: p.expect(token.IF).Pos}
+	stmt.Cond = p.parseExpr()
+	stmt.Body = p.parseBlock()
+	if p.peek().Type == token.ELSE {
+		p.eat()
+		if p.peek().Type == token.IF {
+			stmt.Else = p.parseIf()
+		} else {
+			stmt.Else = p.parseBlock()
+		}
+	}
+	return stmt
+}
+
+func (p *Parser) parseWhile() *ast.WhileStmt {
+	stmt := &ast.WhileStmt{While: p.expect(token.WHILE).Pos}
+	stmt.Cond = p.parseExpr()
+	stmt.Body = p.parseLoopBody()
+	return stmt
+}
+
+// parseFor parses both for k in dict { ... } and the C-style for init; cond; post { ... }.
+func (p *Parser) parseFor() ast.Stmt {
+	forPos := p.expect(token.FOR).Pos
+	if next := p.peekN(2); next[0].Type == token.IDENT && next[1].Type == token.IN {
+		stmt := &ast.ForInStmt{For: forPos, Key: p.parseIdent()}
+		stmt.In = p.expect(token.IN).Pos
+		stmt.X = p.parseExpr()
+		stmt.Body = p.parseLoopBody()
+		return stmt
+	}
+
+	stmt := &ast.ForStmt{For: forPos}
+	if p.peek().Type != token.SEMICOLON {
+		stmt.Init = p.parseSimpleStmt()
+	}
+	p.expect(token.SEMICOLON)
+	if p.peek().Type != token.SEMICOLON {
+		stmt.Cond = p.parseExpr()
+	}
+	p.expect(token.SEMICOLON)
+	if p.peek().Type != token.LCURLY_BRACKET {
+		stmt.Post = p.parseSimpleStmt()
+	}
+	stmt.Body = p.parseLoopBody()
+	return stmt
+}
+
+func (p *Parser) parseLoopBody() *ast.Block {
+	p.loopDepth++
+	defer func() { p.loopDepth-- }()
+	return p.parseBlock()
+}
+
+// parseSimpleStmt parses a function call, an assignment (x = 1, x += 1, x -= 1) or an
+// increment/decrement (x++, x--).
+func (p *Parser) parseSimpleStmt() ast.Stmt {
+	if next := p.peekN(2); next[1].Type == token.LPAREN {
+		return p.parseCallStmt()
+	}
+
+	lhs := p.parseExpr()
+	if lhs == nil {
+		return nil
+	}
+	if getline, ok := lhs.(*ast.GetlineExpr); ok {
+		return getline
+	}
+	t := p.peek()
+	switch t.Type {
+	case token.EQUAL, token.PLUS_EQUAL, token.MINUS_EQUAL:
+		p.eat()
+		p.checkAssignable(lhs)
+		return &ast.AssignStmt{Lhs: lhs, TokPos: t.Pos, Tok: t.Type, Rhs: p.parseExpr()}
+	case token.PLUS_PLUS, token.MINUS_MINUS:
+		p.eat()
+		p.checkAssignable(lhs)
+		return &ast.IncDecStmt{X: lhs, TokPos: t.Pos, Tok: t.Type}
+	default:
+		p.errorf(t.Pos, "unexpected token %s, wanted assignment", t.String())
+	}
+	return nil
+}
+
+// parseCallStmt parses a call, which for print and printf may be followed by a redirection
+// of its output like print(x) > "file" or print(x) | "cmd".
+func (p *Parser) parseCallStmt() ast.Stmt {
+	call := p.parseCall()
+	op := p.peek()
+	switch op.Type {
+	case token.GREATER, token.GREATER_GREATER, token.PIPE:
+		if !p.sameLine(op) {
+			return call
+		}
+	default:
+		return call
+	}
+	p.eat()
+	if name := call.FuncName.Name; name != "print" && name != "printf" {
+		p.errorf(op.Pos, "cannot redirect the output of %s, only print and printf", name)
+	}
+	dest := p.parseBinaryExpr(precConcat)
+	if dest == nil {
+		return nil
+	}
+	return &ast.RedirectStmt{Call: call, OpPos: op.Pos, Op: op.Type, Dest: dest}
+}
+
+// checkAssignable reports an error if x can't be the target of an assignment. Captures
+// (@name) are bound by the pattern and are read-only, only user variables can be assigned.
+func (p *Parser) checkAssignable(x ast.Expr) {
+	switch x := x.(type) {
+	case *ast.Ident:
+		if strings.HasPrefix(x.Name, "@") {
+			p.errorf(x.Pos(), "cannot assign to capture %s", x.Name)
+		}
+	case *ast.IndexExpr, *ast.SelectorExpr:
+		// The container is checked when evaluated, it may not exist yet
+	default:
+		p.errorf(x.Pos(), "cannot assign to %s", ast.Format(x))
+	}
+}
+
 func (p *Parser) parseCall() *ast.Call {
-	call := &ast.Call{FuncName: p.parseIdent()}
+	return p.parseCallArgs(p.parseIdent())
+}
+
+func (p *Parser) parseCallArgs(name *ast.Ident) *ast.Call {
+	call := &ast.Call{FuncName: name}
 	call.Lparen = p.expect(token.LPAREN).Pos
 	call.Args = p.parseArgs()
 	call.Rparen = p.expect(token.RPAREN).Pos
@@ -66,23 +280,215 @@ func (p *Parser) parseArgs() []ast.Expr {
 	var args []ast.Expr
 	for {
 		t := p.peek()
-		if t.Type == token.RPAREN {
+		if t.Type == token.RPAREN || t.Type == token.EOF {
+			break
+		}
+		if arg := p.parseExpr(); arg != nil {
+			args = append(args, arg)
+		} else {
+			p.advance(exprEnd)
+		}
+		if p.peek().Type != token.COMMA {
 			break
 		}
-		args = append(args, p.parseExpr())
+		p.eat()
 	}
 	return args
 }
 
+// Precedence of the binary operators, higher binds tighter.
+const (
+	precOr = iota + 1
+	precAnd
+	precIn
+	precCompare
+	precPipe
+	precConcat
+	precAdd
+	precMul
+)
+
+var binaryPrec = map[token.Type]int{
+	token.OR_OR:         precOr,
+	token.AND_AND:       precAnd,
+	token.IN:            precIn,
+	token.EQUAL_EQUAL:   precCompare,
+	token.BANG_EQUAL:    precCompare,
+	token.LESS:          precCompare,
+	token.LESS_EQUAL:    precCompare,
+	token.GREATER:       precCompare,
+	token.GREATER_EQUAL: precCompare,
+	token.TILDE:         precCompare,
+	token.BANG_TILDE:    precCompare,
+	token.PLUS:          precAdd,
+	token.MINUS:         precAdd,
+	token.STAR:          precMul,
+	token.SLASH:         precMul,
+	token.PERCENT:       precMul,
+}
+
+// concatStart are the tokens that begin the right-hand side of an implicit string
+// concatenation like "a" b.
+var concatStart = map[token.Type]bool{
+	token.IDENT:  true,
+	token.INT:    true,
+	token.FLOAT:  true,
+	token.STRING: true,
+	token.LPAREN: true,
+}
+
 func (p *Parser) parseExpr() ast.Expr {
+	return p.parseBinaryExpr(precOr)
+}
+
+// parseBinaryExpr parses a chain of binary operators with a precedence of at least minPrec
+// using precedence climbing. Like AWK, two expressions next to each other are concatenated.
+// A new line ends the expression so that statements don't need to be separated by ;.
+func (p *Parser) parseBinaryExpr(minPrec int) ast.Expr {
+	x := p.parseUnaryExpr()
+	if x == nil {
+		return nil
+	}
+	for {
+		op := p.peek()
+		if !p.sameLine(op) {
+			return x
+		}
+		if concatStart[op.Type] && precConcat >= minPrec {
+			y := p.parseBinaryExpr(precConcat + 1)
+			if y == nil {
+				return x
+			}
+			x = &ast.ConcatExpr{X: x, Y: y}
+			continue
+		}
+		if op.Type == token.PIPE && precPipe >= minPrec {
+			x = p.parseGetline(x)
+			if x == nil {
+				return nil
+			}
+			continue
+		}
+		prec, ok := binaryPrec[op.Type]
+		if !ok || prec < minPrec {
+			return x
+		}
+		p.eat()
+		y := p.parseBinaryExpr(prec + 1)
+		if y == nil {
+			return x
+		}
+		x = &ast.BinaryExpr{X: x, OpPos: op.Pos, Op: op.Type, Y: y}
+	}
+}
+
+// parseGetline parses the | getline var that follows the command cmd.
+func (p *Parser) parseGetline(cmd ast.Expr) ast.Expr {
+	x := &ast.GetlineExpr{Cmd: cmd, Pipe: p.expect(token.PIPE).Pos}
+	x.Getline = p.expect(token.GETLINE).Pos
+	if x.Var = p.parsePrimaryExpr(); x.Var == nil {
+		return nil
+	}
+	p.checkAssignable(x.Var)
+	return x
+}
+
+func (p *Parser) parseUnaryExpr() ast.Expr {
+	switch t := p.peek(); t.Type {
+	case token.MINUS, token.BANG:
+		p.eat()
+		x := p.parseUnaryExpr()
+		if x == nil {
+			return nil
+		}
+		return &ast.UnaryExpr{OpPos: t.Pos, Op: t.Type, X: x}
+	}
+	return p.parsePrimaryExpr()
+}
+
+// parsePrimaryExpr parses an operand followed by any number of index (x[1]), slice (x[1:2])
+// or member (x.name) accesses.
+func (p *Parser) parsePrimaryExpr() ast.Expr {
+	x := p.parseOperand()
+	if x == nil {
+		return nil
+	}
+	for {
+		next := p.peek()
+		if !p.sameLine(next) {
+			return x
+		}
+		switch next.Type {
+		case token.LSQUARE_BRACKET:
+			x = p.parseIndexOrSlice(x)
+			if x == nil {
+				return nil
+			}
+		case token.PERIOD:
+			p.eat()
+			x = &ast.SelectorExpr{X: x, Sel: p.parseIdent()}
+		default:
+			return x
+		}
+	}
+}
+
+func (p *Parser) parseIndexOrSlice(x ast.Expr) ast.Expr {
+	lbrack := p.expect(token.LSQUARE_BRACKET).Pos
+	var low ast.Expr
+	if p.peek().Type != token.COLON {
+		if low = p.parseExpr(); low == nil {
+			return nil
+		}
+	}
+	if p.peek().Type != token.COLON {
+		rbrack := p.expect(token.RSQUARE_BRACKET).Pos
+		return &ast.IndexExpr{X: x, Lbrack: lbrack, Index: low, Rbrack: rbrack}
+	}
+
+	p.eat()
+	slice := &ast.SliceExpr{X: x, Lbrack: lbrack, Low: low}
+	if p.peek().Type != token.RSQUARE_BRACKET {
+		if slice.High = p.parseExpr(); slice.High == nil {
+			return nil
+		}
+	}
+	slice.Rbrack = p.expect(token.RSQUARE_BRACKET).Pos
+	return slice
+}
+
+func (p *Parser) parseOperand() ast.Expr {
 	t := p.peek()
 	switch t.Type {
 	case token.IDENT:
-		return p.parseIdent()
+		ident := p.parseIdent()
+		if next := p.peek(); next.Type == token.LPAREN && p.sameLine(next) {
+			return p.parseCallArgs(ident)
+		}
+		return ident
 	case token.STRING:
 		return p.parseString()
+	case token.INT:
+		tok := p.eat()
+		return &ast.Int{ValuePos: tok.Pos, Value: tok.Lit}
+	case token.FLOAT:
+		tok := p.eat()
+		return &ast.Float{ValuePos: tok.Pos, Value: tok.Lit}
+	case token.REGEX:
+		tok := p.eat()
+		return &ast.Regex{ValuePos: tok.Pos, Value: tok.Lit[1 : len(tok.Lit)-1]}
+	case token.LPAREN:
+		paren := &ast.ParenExpr{Lparen: p.eat().Pos}
+		paren.X = p.parseExpr()
+		paren.Rparen = p.expect(token.RPAREN).Pos
+		if paren.X == nil {
+			return nil
+		}
+		return paren
 	case token.LCURLY_BRACKET:
 		return p.parseDict()
+	case token.LSQUARE_BRACKET:
+		return p.parseList()
 	default:
 		p.errorf(t.Pos, "unexpected token %s, wanted expression", t.String())
 	}
@@ -99,6 +505,28 @@ func (p *Parser) parseIdent() *ast.Ident {
 	return &ast.Ident{NamePos: tok.Pos, Name: tok.Lit}
 }
 
+func (p *Parser) parseList() ast.Expr {
+	list := &ast.List{Lbrack: p.expect(token.LSQUARE_BRACKET).Pos}
+	for {
+		t := p.peek()
+		if t.Type == token.RSQUARE_BRACKET || t.Type == token.EOF {
+			break
+		}
+		elt := p.parseExpr()
+		if elt == nil {
+			p.advance(exprEnd)
+			return &ast.BadExpr{From: list.Lbrack, To: p.peek().Pos}
+		}
+		list.Elts = append(list.Elts, elt)
+		if p.peek().Type != token.COMMA {
+			break
+		}
+		p.eat()
+	}
+	list.Rbrack = p.expect(token.RSQUARE_BRACKET).Pos
+	return list
+}
+
 func (p *Parser) parseDict() ast.Expr {
 	dict := &ast.Dict{}
 	dict.LCurly = p.expect(token.LCURLY_BRACKET).Pos
@@ -12,6 +12,11 @@ type Parser struct {
 	tokens []lexer.Token
 	file   *token.File
 	pos    int
+	prev   lexer.Token // last token returned by eat
+
+	loopDepth  int             // number of enclosing loops, break and continue are only valid inside one
+	inFunc     bool            // return is only valid inside a function
+	constraint *ast.Constraint // the constraint being parsed, its guard may be inside its pattern
 
 	errors token.ErrorList
 }
@@ -50,11 +55,18 @@ func ParseFile(filename string, src []byte, opts *Options) (prog *ast.Program, e
 		}
 
 		switch tok.Type {
-		case token.LPAREN:
+		case token.LPAREN, token.LSQUARE_BRACKET, token.STRING, token.PATTERN:
 			patternAction := p.parsePatternAction()
 			prog.Patterns = append(prog.Patterns, patternAction)
+		case token.FUNC:
+			prog.Funcs = append(prog.Funcs, p.parseFuncDecl())
+		case token.BEGIN, token.END, token.BEGINFILE, token.ENDFILE:
+			special := &ast.SpecialBlock{KindPos: p.eat().Pos, Kind: tok.Type}
+			special.Action = p.parseAction()
+			prog.Specials = append(prog.Specials, special)
 		default:
-			p.error(tok.Pos, fmt.Errorf("unexpected token %s, wanted pattern or action block", tok.String()))
+			p.error(tok.Pos, fmt.Errorf("unexpected token %s, wanted pattern, action block or func", tok.String()))
+			p.eat()
 		}
 	}
 	return
@@ -67,11 +79,20 @@ func (p *Parser) eat() (tok lexer.Token) {
 			continue
 		}
 		p.pos++
+		p.prev = tok
 		return tok
 	}
 	return lexer.Token{Type: token.EOF}
 }
 
+// sameLine is true if tok is on the same line as the previous token.
+func (p *Parser) sameLine(tok lexer.Token) bool {
+	if tok.Type == token.EOF || !p.prev.Pos.IsValid() {
+		return true
+	}
+	return p.file.Line(p.prev.Pos) == p.file.Line(tok.Pos)
+}
+
 func (p *Parser) eatAll(tokenType token.Type) token.Type {
 	for {
 		if p.pos >= len(p.tokens) {
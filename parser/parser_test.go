@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/masp/awktree/ast"
+	"github.com/masp/awktree/token"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -13,6 +14,34 @@ func TestParse(t *testing.T) {
 		`(identifier){print(@)}`,
 		`(binary_expression operator: "!=" right: (null)){}`,
 		`(id){print({id:"test",id2:@,id3:{id4:"test"}})}`,
+		`(id) @n {x = n;x += 2;x -= 1;x++;x--;print(x,@n)}`,
+		`(id) @n {if n == "a" {print(n)} else if n != "b" {print(n)} else {print(n)}}`,
+		`(id){i = 0;while i < 10 {i++;if i >= 5 {break}}}`,
+		`(id){for i = 0; i <= 3; i++ {continue};for ;; {break}}`,
+		`(id){for k in {a:1} {print(k)}}`,
+		`(id) @n {seen[@n]++;d[n][1] = 1;delete d[n];delete d;print("a" in d && !(1 in d))}`,
+		`(id){x = -a + b * (c - 1) % 2 / 1.5;y = a "-" f(b);z = !a && b || c >= 1e3}`,
+		`(id){l = [];l = append([1,"a",[n]],{k:1});print(len(l),l[0][1:])}`,
+		`(id) @n {if n ~ /^[a-z]+$/ && n !~ "x" || 1 / 2 ~ /x\/y/ {print(match(n,/(a)(b)?/))};sub(/a/,"b",@n);x = gsub(/a/,"$1",n)}`,
+		`(id) @n {print(n) > "out" n ".txt";printf("%s",n) >> dir "/log";eprint(n);x = close("out")}`,
+		`(id) @n {print(n) | "sort -r";"date" | getline d;while ("ls " dir | getline f) > 0 {print(f)};x = system("true")}`,
+		`(id) @fn {print(d["k"]);print(d.k.j);print(s[1:3]);print(s[:2]);print(s[1:]);d.x = 1;print(@fn.name[0])}`,
+		`(id) @n where n == "forbidden" && len(n) > 3 {print(n)}`,
+		`(id) where @ ~ /^_/ {}`,
+		`(id) @n where n != "x"`,
+		`((identifier) @id (#match? @id "^[A-Z]"))`,
+		`(block (expression_statement)+ @stmts){print(len(stmts))}`,
+		`((comment)* @docs . (func_decl name: (id) @name) (id)?)`,
+		`[(call) (new_exp)] @c {print(c)}`,
+		`(bin operator: ["+" "-"] @op right: [(num) [(str) (id)]]*){}`,
+		`(func_decl name: (id) @n !type_parameters body: _)`,
+		`(bin left: _ @l "!=" @op right: (_)* @r){print(l,op,r)}`,
+		`"==" @eq {print(eq)}`,
+		`(block . (stmt) _? .){}`,
+		"`let @v@ = null` @d {print(d,v)}",
+		`(call function: (id) @fn (#eq? @fn "f") arguments: (args (id) @a (#not-any-of? @a "x" "y")))`,
+		`(call) @c inside (func_decl name: (id) @f) where f == "init" not inside (try_stmt) {print(c,f)}`,
+		`(func_decl) not has (return_stmt) has "await"`,
 	}
 
 	for _, tt := range tests {
@@ -26,3 +55,76 @@ func TestParse(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSpecialBlocks(t *testing.T) {
+	prog, err := ParseFile("<test>", []byte(`BEGIN {n = 0} (id){n++} ENDFILE {print(n)} END {print("done")}`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, prog.Specials, 3) {
+		assert.Equal(t, token.BEGIN, prog.Specials[0].Kind)
+		assert.Equal(t, token.ENDFILE, prog.Specials[1].Kind)
+		assert.Equal(t, token.END, prog.Specials[2].Kind)
+	}
+	assert.Len(t, prog.Patterns, 1)
+	assert.Equal(t, "BEGIN {n = 0}\n\nENDFILE {print(n)}\n\nEND {print(\"done\")}\n\n(id){n++}", strings.TrimSpace(ast.Format(prog)))
+}
+
+func TestParseCaptureMinus(t *testing.T) {
+	// dashes are part of capture names in patterns, but not in expressions
+	prog, err := ParseFile("<test>", []byte("(number) @n-1 where @n-1 > 0 {print(@n-1)}\n(id) @id-2 inside ((x) @x where @x-2) {}"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "(number) @n-1 where @n - 1 > 0 {print(@n - 1)}\n\n(id) @id-2 inside ((x) @x) where @x - 2 {}", strings.TrimSpace(ast.Format(prog)))
+}
+
+func TestParseFuncs(t *testing.T) {
+	src := "func add(a, b) {\n  return a + b\n}\nfunc log() { return }\n(id) @n {print(add(n, 1))}"
+	prog, err := ParseFile("<test>", []byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, prog.Funcs, 2) {
+		assert.Equal(t, "add", prog.Funcs[0].Name.Name)
+		assert.Len(t, prog.Funcs[0].Params, 2)
+		assert.Nil(t, prog.Funcs[1].Body.Stmts[0].(*ast.ReturnStmt).Result)
+	}
+	assert.Equal(t, "func add(a,b) {return a + b}\nfunc log() {return}\n(id) @n {print(add(n,1))}", strings.TrimSpace(ast.Format(prog)))
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`(id) @n {@n = "x"}`, "cannot assign to capture @n"},
+		{`(id) {@++}`, "cannot assign to capture @"},
+		{`(id) {"x" = 1}`, "wanted statement"},
+		{`(id) {x}`, "wanted assignment"},
+		{`(id) {break}`, "break is not in a loop"},
+		{`(id) {if 1 {continue}}`, "continue is not in a loop"},
+		{`(id) {return 1}`, "return is not in a function"},
+		{`(id) {len(x) > "f"}`, "cannot redirect the output of len"},
+		{`(id) {"cmd" | getline "x"}`, "cannot assign to \"x\""},
+		{`(id) {"cmd" | x}`, "expected GETLINE"},
+		{`func f(a b) {}`, "expected RPAREN"},
+		{`(id) where`, "expected"},
+		{`((id) @id (#eq? @id 1))`, "bad token in predicate #eq?: INT"},
+		{`(id 1)`, "bad token in node pattern: INT"},
+		{`[(id) 1]`, "bad token in alternation: INT"},
+		{`(id !"x")`, "expected IDENT, got \"x\""},
+		{`(id) not (x)`, "expected inside or has"},
+		{`(id (x) where 1)`, "bad token in node pattern: WHERE"},
+		{`(id) inside ((x) where 1) where 2`, "unexpected token where"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := ParseFile("<test>", []byte(tt.src), nil)
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.want)
+			}
+		})
+	}
+}
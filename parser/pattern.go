@@ -4,45 +4,206 @@ import (
 	"strings"
 
 	"github.com/masp/awktree/ast"
+	"github.com/masp/awktree/lexer"
 	"github.com/masp/awktree/token"
 )
 
+// parsePatternAction parses a pattern followed by an optional where guard, constraints and
+// an optional action, like (identifier) @n where n == "x" not inside (try_statement) { ... }.
 func (p *Parser) parsePatternAction() *ast.PatternAction {
-	pattern := p.parsePattern()
-	action := p.parseAction()
-	return &ast.PatternAction{Pattern: pattern, Action: action}
+	pa := &ast.PatternAction{Pattern: p.parsePatternOrAlternation()}
+	if p.peek().Type == token.WHERE {
+		pa.Where = p.eat().Pos
+		pa.Guard = p.parseExpr()
+	}
+	for p.matches(token.NOT, token.INSIDE, token.HAS) {
+		pa.Constraints = append(pa.Constraints, p.parseConstraint())
+	}
+	if p.peek().Type == token.LCURLY_BRACKET {
+		pa.Action = p.parseAction()
+	}
+	return pa
+}
+
+// parseConstraint parses [not] inside or has followed by a pattern and an optional guard. The
+// guard can follow the pattern, or be the last thing inside its parentheses:
+//
+//	inside (function_declaration name: (identifier) @f where f == "init")
+func (p *Parser) parseConstraint() *ast.Constraint {
+	c := &ast.Constraint{}
+	if p.peek().Type == token.NOT {
+		c.NotPos = p.eat().Pos
+	}
+	tok := p.eat()
+	if tok.Type != token.INSIDE && tok.Type != token.HAS {
+		p.errorf(tok.Pos, "expected inside or has, got %s", tok.String())
+	}
+	c.KindPos, c.Kind = tok.Pos, tok.Type
+
+	p.constraint = c
+	c.Pattern = p.parsePatternOrAlternation()
+	p.constraint = nil
+	if c.Guard == nil && p.peek().Type == token.WHERE {
+		c.Where = p.eat().Pos
+		c.Guard = p.parseExpr()
+	}
+	return c
+}
+
+func (p *Parser) parsePatternOrAlternation() ast.Pattern {
+	switch t := p.peek(); {
+	case t.Type == token.LSQUARE_BRACKET:
+		return p.parseAlternation()
+	case t.Type == token.STRING || isWildcard(t):
+		return p.parseAnonPattern()
+	case t.Type == token.PATTERN:
+		return p.parseExamplePattern()
+	}
+	return p.parsePattern()
+}
+
+// isWildcard is true for the bare _, which matches any node unlike (_).
+func isWildcard(tok lexer.Token) bool {
+	return tok.Type == token.IDENT && tok.Lit == "_"
 }
 
 func (p *Parser) parsePattern() *ast.QueryPattern {
 	pattern := &ast.QueryPattern{Lparen: p.expect(token.LPAREN).Pos}
-	pattern.Symbol = p.parseIdent()
+	if p.peek().Type == token.IDENT {
+		pattern.Symbol = p.parseIdent()
+	} // otherwise it's a group like ((identifier) @id (#eq? @id "x"))
 loop:
 	for {
 		var arg ast.Node
 		t := p.peek()
 		switch t.Type {
 		case token.IDENT:
+			if isWildcard(t) && p.peekN(2)[1].Type != token.COLON {
+				arg = p.parseAnonPattern()
+				break
+			}
 			// Field names
 			pf := &ast.PatternField{Name: p.parseIdent()}
 			pf.Colon = p.expect(token.COLON).Pos
 			arg = pf
+		case token.BANG:
+			arg = &ast.NegatedField{Bang: p.eat().Pos, Name: p.parseIdent()}
 		case token.STRING:
-			// Anonymouse nodes
-			arg = p.parseString()
+			// Anonymous nodes
+			arg = p.parseAnonPattern()
 		case token.LPAREN:
-			// Subnode
-			arg = p.parsePattern()
+			if isPredicate(p.peekN(2)[1]) {
+				arg = p.parsePredicate()
+			} else {
+				// Subnode
+				arg = p.parsePattern()
+			}
+		case token.LSQUARE_BRACKET:
+			arg = p.parseAlternation()
+		case token.PERIOD:
+			arg = &ast.Anchor{Period: p.eat().Pos}
+		case token.WHERE:
+			if p.constraint == nil || p.constraint.Guard != nil {
+				p.errorf(t.Pos, "bad token in node pattern: %v", t.Type)
+				break loop
+			}
+			p.constraint.Where = p.eat().Pos
+			p.constraint.Guard = p.parseExpr()
+			break loop
 		case token.RPAREN:
 			break loop
 		default:
 			p.errorf(t.Pos, "bad token in node pattern: %v", t.Type)
+			break loop
 		}
 		pattern.Args = append(pattern.Args, arg)
 	}
 	pattern.Rparen = p.expect(token.RPAREN).Pos
-	alias := p.peek()
-	if alias.Type == token.IDENT && strings.HasPrefix(alias.Lit, "@") {
+	pattern.QuantPos, pattern.Quantifier, pattern.Capture = p.parsePatternSuffix()
+	return pattern
+}
+
+// parseAlternation parses [ ... ], whose branches are patterns, alternations or anonymous
+// nodes like "+".
+func (p *Parser) parseAlternation() *ast.QueryAlternation {
+	alt := &ast.QueryAlternation{Lbrack: p.expect(token.LSQUARE_BRACKET).Pos}
+loop:
+	for {
+		t := p.peek()
+		switch t.Type {
+		case token.LPAREN, token.LSQUARE_BRACKET, token.STRING:
+			alt.Branches = append(alt.Branches, p.parsePatternOrAlternation())
+		case token.RSQUARE_BRACKET:
+			break loop
+		default:
+			p.errorf(t.Pos, "bad token in alternation: %v", t.Type)
+			break loop
+		}
+	}
+	alt.Rbrack = p.expect(token.RSQUARE_BRACKET).Pos
+	alt.QuantPos, alt.Quantifier, alt.Capture = p.parsePatternSuffix()
+	return alt
+}
+
+// parseAnonPattern parses an anonymous node like "!=" @op or the wildcard _.
+func (p *Parser) parseAnonPattern() *ast.AnonPattern {
+	pattern := &ast.AnonPattern{}
+	if p.peek().Type == token.STRING {
+		pattern.Name = p.parseString()
+	} else {
+		pattern.Name = p.parseIdent()
+	}
+	pattern.QuantPos, pattern.Quantifier, pattern.Capture = p.parsePatternSuffix()
+	return pattern
+}
+
+// parseExamplePattern parses a snippet of code like `let @v@ = null` and its capture.
+func (p *Parser) parseExamplePattern() *ast.ExamplePattern {
+	tok := p.expect(token.PATTERN)
+	pattern := &ast.ExamplePattern{ValuePos: tok.Pos, Value: tok.Lit[1 : len(tok.Lit)-1]}
+	if alias := p.peek(); alias.Type == token.IDENT && strings.HasPrefix(alias.Lit, "@") {
 		pattern.Capture = p.parseIdent()
 	}
 	return pattern
 }
+
+// parsePatternSuffix parses the optional quantifier and capture after a pattern, like )+ @stmts.
+func (p *Parser) parsePatternSuffix() (quantPos token.Pos, quantifier token.Type, capture *ast.Ident) {
+	if q := p.peek(); q.Type == token.STAR || q.Type == token.PLUS || q.Type == token.QUESTION {
+		quantPos = p.eat().Pos
+		quantifier = q.Type
+	}
+	alias := p.peek()
+	if alias.Type == token.IDENT && strings.HasPrefix(alias.Lit, "@") {
+		capture = p.parseIdent()
+	}
+	return
+}
+
+func isPredicate(tok lexer.Token) bool {
+	return tok.Type == token.IDENT && strings.HasPrefix(tok.Lit, "#")
+}
+
+// parsePredicate parses a predicate like (#match? @id "^[A-Z]"), whose arguments are
+// captures or strings.
+func (p *Parser) parsePredicate() *ast.QueryPredicate {
+	pred := &ast.QueryPredicate{Lparen: p.expect(token.LPAREN).Pos}
+	pred.Name = p.parseIdent()
+loop:
+	for {
+		t := p.peek()
+		switch {
+		case t.Type == token.IDENT && strings.HasPrefix(t.Lit, "@"):
+			pred.Args = append(pred.Args, p.parseIdent())
+		case t.Type == token.STRING:
+			pred.Args = append(pred.Args, p.parseString())
+		case t.Type == token.RPAREN:
+			break loop
+		default:
+			p.errorf(t.Pos, "bad token in predicate %s: %v, wanted capture or string", pred.Name.Name, t.Type)
+			break loop
+		}
+	}
+	pred.Rparen = p.expect(token.RPAREN).Pos
+	return pred
+}
@@ -15,6 +15,7 @@ const (
 	FLOAT   // e.g. 123.456
 	STRING  // e.g. "foo"
 	PATTERN // e.g. `foo()`
+	REGEX   // e.g. /fo+/
 	literal_end
 
 	LCURLY_BRACKET
@@ -33,15 +34,49 @@ const (
 	PLUS
 	MINUS
 	SLASH
+	PERCENT
 
 	BANG
 	LESS
 	GREATER
 	LESS_EQUAL
 	GREATER_EQUAL
+	GREATER_GREATER
 	EQUAL
 	BANG_EQUAL
 	EQUAL_EQUAL
+	PLUS_EQUAL
+	MINUS_EQUAL
+	PLUS_PLUS
+	MINUS_MINUS
+	AND_AND
+	OR_OR
+	TILDE
+	BANG_TILDE
+	PIPE
+	QUESTION
+
+	keyword_begin
+	IF
+	ELSE
+	WHILE
+	FOR
+	IN
+	BREAK
+	CONTINUE
+	DELETE
+	BEGIN
+	END
+	BEGINFILE
+	ENDFILE
+	FUNC
+	RETURN
+	GETLINE
+	WHERE
+	INSIDE
+	HAS
+	NOT
+	keyword_end
 
 	EOF Type = 255 // must be at end
 )
@@ -54,6 +89,7 @@ var types = [...]string{
 	FLOAT:           "FLOAT",
 	STRING:          "STRING",
 	PATTERN:         "PATTERN",
+	REGEX:           "REGEX",
 	LCURLY_BRACKET:  "LCURLY_BRACKET",
 	RCURLY_BRACKET:  "RCURLY_BRACKET",
 	LSQUARE_BRACKET: "LSQUARE_BRACKET",
@@ -68,17 +104,114 @@ var types = [...]string{
 	PLUS:            "PLUS",
 	MINUS:           "MINUS",
 	SLASH:           "SLASH",
+	PERCENT:         "PERCENT",
 	BANG:            "BANG",
 	LESS:            "LESS",
 	GREATER:         "GREATER",
 	LESS_EQUAL:      "LESS_EQUAL",
 	GREATER_EQUAL:   "GREATER_EQUAL",
+	GREATER_GREATER: "GREATER_GREATER",
 	EQUAL:           "EQUAL",
 	BANG_EQUAL:      "BANG_EQUAL",
 	EQUAL_EQUAL:     "EQUAL_EQUAL",
+	PLUS_EQUAL:      "PLUS_EQUAL",
+	MINUS_EQUAL:     "MINUS_EQUAL",
+	PLUS_PLUS:       "PLUS_PLUS",
+	MINUS_MINUS:     "MINUS_MINUS",
+	AND_AND:         "AND_AND",
+	OR_OR:           "OR_OR",
+	TILDE:           "TILDE",
+	BANG_TILDE:      "BANG_TILDE",
+	PIPE:            "PIPE",
+	QUESTION:        "QUESTION",
+	IF:              "IF",
+	ELSE:            "ELSE",
+	WHILE:           "WHILE",
+	FOR:             "FOR",
+	IN:              "IN",
+	BREAK:           "BREAK",
+	CONTINUE:        "CONTINUE",
+	DELETE:          "DELETE",
+	BEGIN:           "BEGIN",
+	END:             "END",
+	BEGINFILE:       "BEGINFILE",
+	ENDFILE:         "ENDFILE",
+	FUNC:            "FUNC",
+	RETURN:          "RETURN",
+	GETLINE:         "GETLINE",
+	WHERE:           "WHERE",
+	INSIDE:          "INSIDE",
+	HAS:             "HAS",
+	NOT:             "NOT",
 	EOF:             "EOF",
 }
 
+// ops is the source representation of each operator, used when formatting code.
+var ops = [...]string{
+	LCURLY_BRACKET:  "{",
+	RCURLY_BRACKET:  "}",
+	LSQUARE_BRACKET: "[",
+	RSQUARE_BRACKET: "]",
+	LPAREN:          "(",
+	RPAREN:          ")",
+	COMMA:           ",",
+	PERIOD:          ".",
+	SEMICOLON:       ";",
+	COLON:           ":",
+	STAR:            "*",
+	PLUS:            "+",
+	MINUS:           "-",
+	SLASH:           "/",
+	PERCENT:         "%",
+	BANG:            "!",
+	LESS:            "<",
+	GREATER:         ">",
+	LESS_EQUAL:      "<=",
+	GREATER_EQUAL:   ">=",
+	GREATER_GREATER: ">>",
+	EQUAL:           "=",
+	BANG_EQUAL:      "!=",
+	EQUAL_EQUAL:     "==",
+	PLUS_EQUAL:      "+=",
+	MINUS_EQUAL:     "-=",
+	PLUS_PLUS:       "++",
+	MINUS_MINUS:     "--",
+	AND_AND:         "&&",
+	OR_OR:           "||",
+	TILDE:           "~",
+	BANG_TILDE:      "!~",
+	PIPE:            "|",
+	QUESTION:        "?",
+	IF:              "if",
+	ELSE:            "else",
+	WHILE:           "while",
+	FOR:             "for",
+	IN:              "in",
+	BREAK:           "break",
+	CONTINUE:        "continue",
+	DELETE:          "delete",
+	BEGIN:           "BEGIN",
+	END:             "END",
+	BEGINFILE:       "BEGINFILE",
+	ENDFILE:         "ENDFILE",
+	FUNC:            "func",
+	RETURN:          "return",
+	GETLINE:         "getline",
+	WHERE:           "where",
+	INSIDE:          "inside",
+	HAS:             "has",
+	NOT:             "not",
+}
+
+// Op returns the operator as it is written in source (e.g. "+=" for PLUS_EQUAL), or the
+// name of the type if it is not an operator.
+func (tok Type) Op() string {
+	if 0 <= tok && tok < Type(len(ops)) && ops[tok] != "" {
+		return ops[tok]
+	}
+	return tok.String()
+}
+
 func (tok Type) String() string {
 	s := ""
 	if 0 <= tok && tok < Type(len(types)) {
@@ -93,3 +226,7 @@ func (tok Type) String() string {
 func (tok Type) IsLiteral() bool {
 	return literal_begin < tok && tok < literal_end
 }
+
+func (tok Type) IsKeyword() bool {
+	return keyword_begin < tok && tok < keyword_end
+}